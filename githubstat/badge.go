@@ -0,0 +1,267 @@
+package githubstat
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	BadgeMetricMerged  = "merged"
+	BadgeMetricCommits = "commits"
+
+	DefaultBadgeDir = "badges"
+)
+
+// badge is a shields.io style badge of a label and a value.
+type badge struct {
+	Label       string
+	Value       string
+	Color       string
+	Width       int
+	LabelWidth  int
+	ValueWidth  int
+	LabelCenter int
+	ValueCenter int
+}
+
+// card is a contribution card of a user in the stat period.
+type card struct {
+	Name          string
+	Period        string
+	Merged        int
+	MergedCommits int
+	Repos         int
+	Width         int
+}
+
+// textWidth estimates width of text in pixels of 11px Verdana, wide characters like CJK take about double width.
+func textWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		if r < 0x80 {
+			width += 7
+		} else {
+			width += 12
+		}
+	}
+	return width
+}
+
+func newBadge(label string, value string) *badge {
+	b := &badge{Label: label, Value: value, Color: "#4c1", LabelWidth: textWidth(label) + 10, ValueWidth: textWidth(value) + 10}
+	if value == "0" {
+		b.Color = "#9f9f9f"
+	}
+	b.Width = b.LabelWidth + b.ValueWidth
+	b.LabelCenter = b.LabelWidth / 2
+	b.ValueCenter = b.LabelWidth + b.ValueWidth/2
+	return b
+}
+
+// badgeLabel returns e.g. "merged PRs to kubernetes", repos are named by their owner if all of them have the same one.
+func badgeLabel(metric string, metrics []*PullRequestMetrics) string {
+	label := "merged PRs"
+	if metric == BadgeMetricCommits {
+		label = "merged commits"
+	}
+	owners := make(map[string]bool)
+	var owner string
+	for _, m := range metrics {
+		if repo, err := ParseRepo(m.Repo); err == nil {
+			owner = *repo.OwnerName
+			owners[owner] = true
+		}
+	}
+	switch len(owners) {
+	case 0:
+		return label
+	case 1:
+		return label + " to " + owner
+	}
+	return fmt.Sprintf("%s to %d orgs", label, len(owners))
+}
+
+// userBadge returns the badge of the metric of the user, metrics are those of every user and repo.
+func userBadge(metric string, userName string, metrics []*PullRequestMetrics) (*badge, error) {
+	metrics = (&metricsQuery{users: []string{userName}}).filter(metrics)
+	sum := total(metrics)
+	switch metric {
+	case "", BadgeMetricMerged:
+		return newBadge(badgeLabel(metric, metrics), fmt.Sprint(sum.Merged)), nil
+	case BadgeMetricCommits:
+		return newBadge(badgeLabel(metric, metrics), fmt.Sprint(sum.MergedCommits)), nil
+	}
+	return nil, fmt.Errorf("unknown metric of badge : %s, must be %q or %q", metric, BadgeMetricMerged, BadgeMetricCommits)
+}
+
+// userCard returns the card of the user in the stat period ending at fetchedAt if it has no end.
+func userCard(user *User, metrics []*PullRequestMetrics, fetchedAt time.Time) *card {
+	metrics = (&metricsQuery{users: []string{user.Name}}).filter(metrics)
+	sum := total(metrics)
+	c := &card{Name: user.Name, Merged: sum.Merged, MergedCommits: sum.MergedCommits}
+	if user.RealName != "" {
+		c.Name = fmt.Sprintf("%s (%s)", user.RealName, user.Name)
+	}
+	// repos with any merged or created PR of the user
+	for _, m := range mergeByRepo(metrics) {
+		if m.Merged+m.MergedCommits+m.Created != 0 {
+			c.Repos++
+		}
+	}
+	endTime := Config.StatEndTime
+	if endTime.IsZero() {
+		endTime = fetchedAt
+	}
+	c.Period = Config.StatBeginTime.Format("2006-01-02") + " ~ " + endTime.Format("2006-01-02")
+	c.Width = 300
+	if w := textWidth(c.Name)*3/2 + 40; w > c.Width {
+		c.Width = w
+	}
+	return c
+}
+
+var badgeTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20">
+<title>{{.Label}}: {{.Value}}</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)">
+<rect width="{{.LabelWidth}}" height="20" fill="#555"/>
+<rect x="{{.LabelWidth}}" width="{{.ValueWidth}}" height="20" fill="{{.Color}}"/>
+<rect width="{{.Width}}" height="20" fill="url(#s)"/>
+</g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelCenter}}" y="15" fill="#010101" fill-opacity=".3">{{.Label}}</text>
+<text x="{{.LabelCenter}}" y="14">{{.Label}}</text>
+<text x="{{.ValueCenter}}" y="15" fill="#010101" fill-opacity=".3">{{.Value}}</text>
+<text x="{{.ValueCenter}}" y="14">{{.Value}}</text>
+</g>
+</svg>
+`))
+
+var cardTemplate = template.Must(template.New("card").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="130">
+<title>contributions of {{.Name}}</title>
+<rect x="0.5" y="0.5" width="{{.Width}}" height="129" rx="6" fill="#fffefe" stroke="#e4e2e2"/>
+<g font-family="Segoe UI,Helvetica,Arial,sans-serif" fill="#333">
+<text x="20" y="30" font-size="16" font-weight="bold" fill="#2f80ed">{{.Name}}</text>
+<text x="20" y="56" font-size="13">Merged PRs: <tspan font-weight="bold">{{.Merged}}</tspan></text>
+<text x="20" y="78" font-size="13">Merged Commits: <tspan font-weight="bold">{{.MergedCommits}}</tspan></text>
+<text x="20" y="100" font-size="13">Repos Touched: <tspan font-weight="bold">{{.Repos}}</tspan></text>
+<text x="20" y="120" font-size="10" fill="#888">{{.Period}}</text>
+</g>
+</svg>
+`))
+
+func renderSVG(t *template.Template, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteBadges fetches metrics of repos, and writes a badge of merged PRs, a badge of merged commits
+// and a contribution card of every user into dir, e.g. "bruceauyeung.svg", "bruceauyeung-commits.svg"
+// and "bruceauyeung-card.svg".
+func WriteBadges(repos []*RepoParameters, dir string) {
+	if dir == "" {
+		dir = DefaultBadgeDir
+	}
+	fetchedAt := time.Now()
+	all := fetchPullRequestMetrics(repos, Config.ThisWeekFirstDay)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		panic(err)
+	}
+	write := func(name string, t *template.Template, data interface{}) {
+		svg, err := renderSVG(t, data)
+		if err != nil {
+			panic(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), svg, 0644); err != nil {
+			panic(err)
+		}
+	}
+	for i := range Config.Users {
+		user := &Config.Users[i]
+		for _, metric := range []string{BadgeMetricMerged, BadgeMetricCommits} {
+			b, err := userBadge(metric, user.Name, all.Overall)
+			if err != nil {
+				panic(err)
+			}
+			name := user.Name + ".svg"
+			if metric != BadgeMetricMerged {
+				name = user.Name + "-" + metric + ".svg"
+			}
+			write(name, badgeTemplate, b)
+		}
+		write(user.Name+"-card.svg", cardTemplate, userCard(user, all.Overall, fetchedAt))
+	}
+	fmt.Printf("badges of %d users are written to %s\n", len(Config.Users), dir)
+}
+
+// serveBadge serves /badge/{user}.svg of merged PRs, or of merged commits by "?metric=commits",
+// query parameters "window" and "repos" select the metrics like the api.
+func (s *Server) serveBadge(w http.ResponseWriter, r *http.Request) {
+	s.serveSVG(w, r, "/badge/", func(user *User, q *metricsQuery, all *AllPullRequestMetrics, refreshedAt time.Time) ([]byte, error) {
+		b, err := userBadge(r.URL.Query().Get("metric"), user.Name, q.filter(windowMetrics(all, q.windows[0])))
+		if err != nil {
+			return nil, err
+		}
+		return renderSVG(badgeTemplate, b)
+	})
+}
+
+// serveCard serves /card/{user}.svg of the stat period, query parameter "repos" selects repos like the api.
+func (s *Server) serveCard(w http.ResponseWriter, r *http.Request) {
+	s.serveSVG(w, r, "/card/", func(user *User, q *metricsQuery, all *AllPullRequestMetrics, refreshedAt time.Time) ([]byte, error) {
+		return renderSVG(cardTemplate, userCard(user, q.filter(all.Overall), refreshedAt))
+	})
+}
+
+func (s *Server) serveSVG(w http.ResponseWriter, r *http.Request, prefix string,
+	render func(user *User, q *metricsQuery, all *AllPullRequestMetrics, refreshedAt time.Time) ([]byte, error)) {
+	all, refreshedAt := s.latest()
+	if all == nil {
+		http.Error(w, "metrics are not fetched yet", http.StatusServiceUnavailable)
+		return
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, prefix), ".svg")
+	var user *User
+	for i := range Config.Users {
+		if Config.Users[i].Name == name {
+			user = &Config.Users[i]
+		}
+	}
+	if user == nil {
+		http.Error(w, fmt.Sprintf("user %s is not configured", name), http.StatusNotFound)
+		return
+	}
+	q, err := parseMetricsQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// badges are of the stat period unless this week is asked for
+	if len(q.windows) != 1 {
+		q.windows = []string{WindowOverall}
+	}
+	if weekDisabled(q.windows[0]) {
+		http.Error(w, "week statistics is disabled because statEndTime is specified", http.StatusNotFound)
+		return
+	}
+	svg, err := render(user, q, all, refreshedAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	// image proxies like GitHub's camo cache badges shortly
+	w.Header().Set("Cache-Control", "max-age=300")
+	w.Write(svg)
+}
@@ -0,0 +1,61 @@
+package githubstat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_serveBadge(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "", "2016-12-24T00:00:00Z")()
+	users := Config.Users
+	defer func() { Config.Users = users }()
+	Config.Users = []User{{Name: "bruceauyeung", RealName: "欧阳钦华"}, {Name: "tanshanshan"}}
+
+	s := newTestServer()
+	handler := s.Handler()
+	s.refresh()
+
+	cases := []struct {
+		url  string
+		code int
+		want []string
+	}{
+		{"/badge/bruceauyeung.svg", http.StatusOK, []string{"<title>merged PRs to kubernetes: 5</title>"}},
+		{"/badge/bruceauyeung.svg?metric=commits&repos=kubernetes/website", http.StatusOK, []string{"<title>merged commits to kubernetes: 2</title>"}},
+		{"/badge/bruceauyeung.svg?window=week", http.StatusOK, []string{"<title>merged PRs to kubernetes: 1</title>"}},
+		{"/badge/tanshanshan.svg?window=week", http.StatusOK, []string{`fill="#9f9f9f"`}},
+		{"/badge/bruceauyeung.svg?metric=reviews", http.StatusBadRequest, nil},
+		{"/badge/nobody.svg", http.StatusNotFound, nil},
+		{"/card/bruceauyeung.svg", http.StatusOK, []string{"欧阳钦华 (bruceauyeung)", "Merged PRs: <tspan font-weight=\"bold\">5</tspan>",
+			"Merged Commits: <tspan font-weight=\"bold\">6</tspan>", "Repos Touched: <tspan font-weight=\"bold\">2</tspan>", "2016-10-01 ~ "}},
+		{"/card/tanshanshan.svg?repos=kubernetes/website", http.StatusOK, []string{"Repos Touched: <tspan font-weight=\"bold\">0</tspan>"}},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", c.url, nil))
+		if w.Code != c.code {
+			t.Errorf("status of %s is %d, want %d", c.url, w.Code, c.code)
+			continue
+		}
+		if c.code == http.StatusOK && w.Header().Get("Content-Type") != "image/svg+xml" {
+			t.Errorf("content type of %s is %s", c.url, w.Header().Get("Content-Type"))
+		}
+		for _, want := range c.want {
+			if !strings.Contains(w.Body.String(), want) {
+				t.Errorf("%s does not contain %q:\n%s", c.url, want, w.Body.String())
+			}
+		}
+	}
+}
+
+func Test_badgeLabel(t *testing.T) {
+	metrics := []*PullRequestMetrics{{Repo: "kubernetes/kubernetes"}, {Repo: "ghe.corp:team/repo"}}
+	if label := badgeLabel(BadgeMetricMerged, metrics); label != "merged PRs to 2 orgs" {
+		t.Errorf("label of repos of 2 owners is %q", label)
+	}
+	if label := badgeLabel(BadgeMetricCommits, nil); label != "merged commits" {
+		t.Errorf("label without repos is %q", label)
+	}
+}
@@ -1,12 +1,29 @@
 package githubstat
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
 	"github.com/google/go-github/github"
 	"golang.org/x/oauth2"
 )
 
+// DefaultHost is the host of repos without host prefix.
+const DefaultHost = "github.com"
+
 type ProxyClient struct {
-	client *github.Client
+	// mu guards clients created on first use, proxy clients are shared by goroutines of the server
+	mu         sync.Mutex
+	host       *Host
+	client     *github.Client
+	httpClient *http.Client
 }
 
 type tokenSource struct {
@@ -17,17 +34,139 @@ func (t *tokenSource) Token() (*oauth2.Token, error) {
 	return t.token, nil
 }
 
+// proxy clients by host name, so that every host has only one client.
+var (
+	proxyClientsMu sync.Mutex
+	proxyClients   = make(map[string]*ProxyClient)
+)
+
+// getProxyClient returns the proxy client of host, github.com is used if host is empty.
+func getProxyClient(host string) *ProxyClient {
+	if host == "" {
+		host = DefaultHost
+	}
+	proxyClientsMu.Lock()
+	defer proxyClientsMu.Unlock()
+	if c, found := proxyClients[host]; found {
+		return c
+	}
+	c := &ProxyClient{host: findHost(host)}
+	proxyClients[host] = c
+	return c
+}
+
+// findHost returns settings of the host, github.com uses top level access token if it is not configured.
+func findHost(name string) *Host {
+	for i := range Config.Hosts {
+		if Config.Hosts[i].Name == name {
+			return &Config.Hosts[i]
+		}
+	}
+	if name != DefaultHost {
+		panic(fmt.Sprintf("host %s is not configured", name))
+	}
+	return &Host{Name: DefaultHost}
+}
+
 // create a github client only once.
 // call Client() and create client only once.
 func (c *ProxyClient) getClient() *github.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if nil == c.client {
+		c.client = github.NewClient(c.lockedHTTPClient())
+		// go-github requires trailing slash of base url and upload url
+		if baseURL := c.getHost().BaseURL; baseURL != "" {
+			c.client.BaseURL = mustParseURL(strings.TrimSuffix(baseURL, "/") + "/")
+		}
+		if uploadURL := c.getHost().UploadURL; uploadURL != "" {
+			c.client.UploadURL = mustParseURL(strings.TrimSuffix(uploadURL, "/") + "/")
+		}
+	}
+
+	return c.client
+}
+
+// getHTTPClient returns the http client authorized by access token, which is shared by
+// github client and requests that github client does not support, such as GraphQL queries.
+func (c *ProxyClient) getHTTPClient() *http.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lockedHTTPClient()
+}
+
+// lockedHTTPClient creates the http client only once, c.mu must be held.
+func (c *ProxyClient) lockedHTTPClient() *http.Client {
+	if nil == c.httpClient {
+		host := c.getHost()
+		accessToken := host.AccessToken
+		if accessToken == "" && host.Name == DefaultHost {
+			accessToken = Config.AccessToken
+		}
 		ts := &tokenSource{
-			&oauth2.Token{AccessToken: Config.AccessToken},
+			&oauth2.Token{AccessToken: accessToken},
 		}
 
-		tc := oauth2.NewClient(oauth2.NoContext, ts)
-		c.client = github.NewClient(tc)
+		var transport http.RoundTripper = newTransport(host)
+		switch Config.FixtureMode {
+		case "":
+		case FixtureModeRecord, FixtureModeReplay:
+			transport = newFixtureTransport(Config.FixtureMode, Config.FixtureDir, transport)
+		default:
+			panic(fmt.Sprintf("unknown fixture mode : %s, must be %q or %q", Config.FixtureMode, FixtureModeRecord, FixtureModeReplay))
+		}
+		ctx := context.WithValue(oauth2.NoContext, oauth2.HTTPClient, &http.Client{Transport: transport})
+		c.httpClient = oauth2.NewClient(ctx, ts)
 	}
 
-	return c.client
+	return c.httpClient
+}
+
+// getGraphQLURL returns url of GraphQL api, which is "api/graphql" next to "api/v3/" for GitHub Enterprise.
+func (c *ProxyClient) getGraphQLURL() string {
+	host := c.getHost()
+	if host.GraphQLURL != "" {
+		return host.GraphQLURL
+	}
+	if host.BaseURL == "" {
+		return DefaultGraphQLURL
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(host.BaseURL, "/"), "/v3") + "/graphql"
+}
+
+func (c *ProxyClient) getHost() *Host {
+	if c.host == nil {
+		c.host = findHost(DefaultHost)
+	}
+	return c.host
+}
+
+func newTransport(host *Host) *http.Transport {
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if host.Proxy != "" {
+		transport.Proxy = http.ProxyURL(mustParseURL(host.Proxy))
+	}
+	if host.CABundle != "" {
+		pem, err := ioutil.ReadFile(host.CABundle)
+		if err != nil {
+			panic(err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			panic(fmt.Sprintf("no certificate found in CA bundle %s", host.CABundle))
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return transport
+}
+
+func mustParseURL(rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		panic(err)
+	}
+	return u
 }
@@ -0,0 +1,146 @@
+package githubstat
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+const (
+	CompareWeek    = "week"
+	CompareMonth   = "month"
+	CompareQuarter = "quarter"
+
+	improvementMark = "▲"
+	dropMark        = "▼"
+)
+
+// previousWindow returns the window before [begin, end) shifted back by a week, a month or a quarter.
+// the window must be no longer than the unit, otherwise it overlaps the previous one.
+func previousWindow(compare string, begin time.Time, end time.Time) (time.Time, time.Time) {
+	var months, days int
+	switch compare {
+	case CompareWeek:
+		days = -7
+	case CompareMonth:
+		months = -1
+	case CompareQuarter:
+		months = -3
+	default:
+		panic(fmt.Sprintf("unknown compare : %s, must be %q, %q or %q", compare, CompareWeek, CompareMonth, CompareQuarter))
+	}
+	previousBegin, previousEnd := begin.AddDate(0, months, days), end.AddDate(0, months, days)
+	if previousEnd.After(begin) {
+		panic(fmt.Sprintf("stat period ( %v ~ %v) is longer than a %s, which overlaps the previous %s", begin, end, compare, compare))
+	}
+	return previousBegin, previousEnd
+}
+
+// fetchPrevious fetches overall metrics of the previous window of the period, which ends now if it has no end.
+// providers are created again because some of them cache PRs of the stat period.
+func (m *PullRequestMetricsRequest) fetchPrevious(period statPeriod) (*OverallPullRequestMetrics, time.Time, time.Time) {
+	begin, end := previousWindow(Config.Compare, period.begin, period.endOrNow())
+
+	fmt.Printf("fetching metrics of the previous %s ( %v ~ %v)\n", Config.Compare, begin, end)
+	previous := &PullRequestMetricsRequest{
+		param:     &MetricsParameters{Repos: m.param.Repos, Dimension: m.param.Dimension},
+		source:    m.source,
+		api:       m.api,
+		users:     m.users,
+		noCompare: true,
+		period:    statPeriod{begin: begin, end: end},
+	}
+	return previous.FetchMetrics().(*AllPullRequestMetrics).OverallPullRequestMetrics, begin, end
+}
+
+// compareColumns are columns of metrics compared with the previous window, fewer closed unmerged PRs are better.
+var compareColumns = []struct {
+	name           string
+	value          func(*PullRequestMetrics) int
+	higherIsBetter bool
+}{
+	{"Merged PRs", func(m *PullRequestMetrics) int { return m.Merged }, true},
+	{"Merged Commits", func(m *PullRequestMetrics) int { return m.MergedCommits }, true},
+	{"LGTM'ed PRs", func(m *PullRequestMetrics) int { return m.LGTMed }, true},
+	{"NonLGTM'ed PRs", func(m *PullRequestMetrics) int { return m.NonLGTMed }, true},
+	{"Created PRs", func(m *PullRequestMetrics) int { return m.Created }, true},
+	{"Closed Unmerged PRs", func(m *PullRequestMetrics) int { return m.ClosedUnmerged }, false},
+}
+
+// formatDelta returns e.g. "12 (+3, +33.3%) ▲", the mark tells an improvement or a drop.
+func formatDelta(current int, previous int, higherIsBetter bool) string {
+	delta := current - previous
+	if delta == 0 {
+		return fmt.Sprintf("%d (0)", current)
+	}
+	percent := "new"
+	if previous != 0 {
+		percent = fmt.Sprintf("%+.1f%%", float64(delta)*100/float64(previous))
+	}
+	mark := improvementMark
+	if (delta > 0) != higherIsBetter {
+		mark = dropMark
+	}
+	return fmt.Sprintf("%d (%+d, %s) %s", current, delta, percent, mark)
+}
+
+// comparisonRows returns rows of metrics merged by user with deltas from the previous window and a "Total" row,
+// users only in the previous window are listed too.
+func comparisonRows(current []*PullRequestMetrics, previous []*PullRequestMetrics) [][]string {
+	previousByUser := make(map[string]*PullRequestMetrics)
+	for _, m := range merge(previous) {
+		previousByUser[m.User] = m
+	}
+	rows := append([]*PullRequestMetrics{}, current...)
+	for _, m := range merge(previous) {
+		if !containsMetricsOf(current, m.User) {
+			rows = append(rows, &PullRequestMetrics{User: m.User})
+		}
+	}
+
+	data := [][]string{}
+	row := func(name string, m *PullRequestMetrics, p *PullRequestMetrics) {
+		r := []string{name}
+		for _, column := range compareColumns {
+			r = append(r, formatDelta(column.value(m), column.value(p), column.higherIsBetter))
+		}
+		data = append(data, r)
+	}
+	for _, m := range rows {
+		p, found := previousByUser[m.User]
+		if !found {
+			p = &PullRequestMetrics{User: m.User}
+		}
+		row(userDisplayName(m.User), m, p)
+	}
+	row("Total", total(current), total(previous))
+	return data
+}
+
+func containsMetricsOf(metrics []*PullRequestMetrics, user string) bool {
+	for _, m := range metrics {
+		if m.User == user {
+			return true
+		}
+	}
+	return false
+}
+
+// showComparison shows metrics merged by user compared with the previous window.
+func (m *OverallPullRequestMetrics) showComparison() {
+	if m.Previous == nil {
+		return
+	}
+	fmt.Printf("\nComparison with the Previous %s ( %v ~ %v), %s improvement, %s drop\n", Config.Compare,
+		m.PreviousBeginTime, m.PreviousEndTime, improvementMark, dropMark)
+	header := []string{"User Name"}
+	for _, column := range compareColumns {
+		header = append(header, column.name)
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(header)
+	table.AppendBulk(comparisonRows(m.Overall, m.Previous))
+	table.Render()
+}
@@ -0,0 +1,79 @@
+package githubstat
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// CompareAPIs fetches pull request metrics of repos by REST API and by GraphQL API, and shows them side by side,
+// so that metrics of the GraphQL provider can be checked against those of the REST provider.
+func CompareAPIs(repos []*RepoParameters) {
+	fetch := func(api string) *AllPullRequestMetrics {
+		fmt.Printf("fetching metrics by %s api\n", api)
+		m := &PullRequestMetricsRequest{api: api}
+		m.SetParameters(&MetricsParameters{Repos: repos})
+		return m.FetchMetrics().(*AllPullRequestMetrics)
+	}
+	rest, graphql := fetch(APIREST), fetch(APIGraphQL)
+	differences := showAPIComparison("Overall", rest.Overall, graphql.Overall)
+	differences += showAPIComparison("This Week", rest.Week, graphql.Week)
+	fmt.Printf("\n%d differences between %s and %s api\n", differences, APIREST, APIGraphQL)
+}
+
+// apiComparisonRows returns rows of metrics by user and repo, a cell is "rest / graphql" if values differ.
+// it also returns the number of cells differing.
+func apiComparisonRows(rest []*PullRequestMetrics, graphql []*PullRequestMetrics) ([][]string, int) {
+	key := func(m *PullRequestMetrics) string { return m.User + "\x00" + m.Repo }
+	graphqlByKey := make(map[string]*PullRequestMetrics)
+	for _, m := range graphql {
+		graphqlByKey[key(m)] = m
+	}
+	rows := append([]*PullRequestMetrics{}, rest...)
+	found := make(map[string]bool)
+	for _, m := range rest {
+		found[key(m)] = true
+	}
+	for _, m := range graphql {
+		if !found[key(m)] {
+			rows = append(rows, &PullRequestMetrics{User: m.User, Repo: m.Repo})
+		}
+	}
+
+	data := [][]string{}
+	var differences int
+	for _, m := range rows {
+		g, found := graphqlByKey[key(m)]
+		if !found {
+			g = &PullRequestMetrics{User: m.User, Repo: m.Repo}
+		}
+		row := []string{userDisplayName(m.User), m.Repo}
+		for _, column := range compareColumns {
+			r, q := column.value(m), column.value(g)
+			if r == q {
+				row = append(row, strconv.Itoa(r))
+			} else {
+				row = append(row, fmt.Sprintf("%d / %d", r, q))
+				differences++
+			}
+		}
+		data = append(data, row)
+	}
+	return data, differences
+}
+
+func showAPIComparison(title string, rest []*PullRequestMetrics, graphql []*PullRequestMetrics) int {
+	data, differences := apiComparisonRows(rest, graphql)
+	fmt.Printf("\n%s Metrics by %s / %s API\n", title, APIREST, APIGraphQL)
+	header := []string{"User Name", "Repository"}
+	for _, column := range compareColumns {
+		header = append(header, column.name)
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(header)
+	table.AppendBulk(data)
+	table.Render()
+	return differences
+}
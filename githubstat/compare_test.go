@@ -0,0 +1,103 @@
+package githubstat
+
+import (
+	"testing"
+)
+
+func Test_previousWindow(t *testing.T) {
+	cases := []struct {
+		compare    string
+		begin, end string
+		// previous window, which is empty if the stat period overlaps it
+		previousBegin, previousEnd string
+	}{
+		{CompareWeek, "2017-04-03T00:00:00Z", "2017-04-10T00:00:00Z", "2017-03-27T00:00:00Z", "2017-04-03T00:00:00Z"},
+		{CompareMonth, "2017-04-01T00:00:00Z", "2017-05-01T00:00:00Z", "2017-03-01T00:00:00Z", "2017-04-01T00:00:00Z"},
+		{CompareQuarter, "2017-04-01T00:00:00Z", "2017-07-01T00:00:00Z", "2017-01-01T00:00:00Z", "2017-04-01T00:00:00Z"},
+		// a period shorter than the unit, e.g. a month until now, is compared with the same days of the previous unit
+		{CompareMonth, "2017-04-01T00:00:00Z", "2017-04-19T00:00:00Z", "2017-03-01T00:00:00Z", "2017-03-19T00:00:00Z"},
+		{CompareWeek, "2017-04-01T00:00:00Z", "2017-07-01T00:00:00Z", "", ""},
+		{CompareMonth, "2017-01-01T00:00:00Z", "2017-04-19T00:00:00Z", "", ""},
+	}
+	for _, c := range cases {
+		func() {
+			defer func() {
+				if r := recover(); r != nil && c.previousBegin != "" {
+					t.Errorf("previous %s of %s ~ %s panics: %v", c.compare, c.begin, c.end, r)
+				}
+			}()
+			b, e := previousWindow(c.compare, *fakeTime(c.begin), *fakeTime(c.end))
+			if c.previousBegin == "" {
+				t.Errorf("previous %s of %s ~ %s is %v ~ %v, which overlaps the period", c.compare, c.begin, c.end, b, e)
+			} else if !b.Equal(*fakeTime(c.previousBegin)) || !e.Equal(*fakeTime(c.previousEnd)) {
+				t.Errorf("previous %s of %s ~ %s is %v ~ %v, want %s ~ %s", c.compare, c.begin, c.end, b, e,
+					c.previousBegin, c.previousEnd)
+			}
+		}()
+	}
+}
+
+func Test_formatDelta(t *testing.T) {
+	cases := []struct {
+		current, previous int
+		higherIsBetter    bool
+		want              string
+	}{
+		{12, 9, true, "12 (+3, +33.3%) ▲"},
+		{6, 8, true, "6 (-2, -25.0%) ▼"},
+		{2, 0, true, "2 (+2, new) ▲"},
+		{3, 1, false, "3 (+2, +200.0%) ▼"},
+		{5, 5, true, "5 (0)"},
+	}
+	for _, c := range cases {
+		if got := formatDelta(c.current, c.previous, c.higherIsBetter); got != c.want {
+			t.Errorf("delta of %d from %d is %q, want %q", c.current, c.previous, got, c.want)
+		}
+	}
+}
+
+func Test_compare(t *testing.T) {
+	defer setStatPeriod("2016-11-01T00:00:00Z", "2016-12-01T00:00:00Z", "2016-11-26T00:00:00Z")()
+	users, hosts, compare := Config.Users, Config.Hosts, Config.Compare
+	defer func() { Config.Users, Config.Hosts, Config.Compare = users, hosts, compare }()
+	Config.Users = []User{{Name: "bruceauyeung"}, {Name: "tanshanshan"}}
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+	// metrics of the previous window are fetched by providers of configured hosts
+	Config.Hosts = []Host{{Name: "compare.test", BaseURL: f.URL + "/"}}
+	defer delete(proxyClients, "compare.test")
+	Config.Compare = CompareMonth
+
+	repo, err := ParseRepo("compare.test:kubernetes/kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &PullRequestMetricsRequest{}
+	m.SetParameters(&MetricsParameters{Repos: []*RepoParameters{repo}})
+	all := m.FetchMetrics().(*AllPullRequestMetrics)
+	if !Config.StatBeginTime.Equal(*fakeTime("2016-11-01T00:00:00Z")) {
+		t.Errorf("stat begin time is changed to %v", Config.StatBeginTime)
+	}
+	if !all.PreviousBeginTime.Equal(*fakeTime("2016-10-01T00:00:00Z")) || !all.PreviousEndTime.Equal(*fakeTime("2016-11-01T00:00:00Z")) {
+		t.Errorf("previous window is %v ~ %v", all.PreviousBeginTime, all.PreviousEndTime)
+	}
+
+	// PR 4 was merged in October, PR 3 was closed unmerged in November
+	rows := comparisonRows(merge(all.Overall), all.Previous)
+	want := [][]string{
+		{"bruceauyeung", "0 (-1, -100.0%) ▼", "0 (-1, -100.0%) ▼"},
+		{"tanshanshan", "0 (0)", "0 (0)"},
+		{"Total", "0 (-1, -100.0%) ▼", "0 (-1, -100.0%) ▼"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("comparison has %d rows, want %d : %v", len(rows), len(want), rows)
+	}
+	for i, row := range want {
+		if rows[i][0] != row[0] || rows[i][1] != row[1] || rows[i][2] != row[2] {
+			t.Errorf("comparison of %s is %v, want %v", row[0], rows[i], row)
+		}
+	}
+	if closed := rows[1][6]; closed != "1 (+1, new) ▼" {
+		t.Errorf("comparison of closed unmerged PRs of tanshanshan is %q", closed)
+	}
+}
@@ -0,0 +1,40 @@
+package githubstat
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_cronScheduleNext(t *testing.T) {
+	cases := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"0 17 * * 5", "2016-12-21T10:00:00Z", "2016-12-23T17:00:00Z"},
+		{"0 17 * * 5", "2016-12-23T17:00:00Z", "2016-12-30T17:00:00Z"},
+		{"*/15 * * * *", "2016-12-23T17:01:30Z", "2016-12-23T17:15:00Z"},
+		{"30 9 1 * *", "2016-12-23T17:00:00Z", "2017-01-01T09:30:00Z"},
+		// either day of month or day of week matches if both are restricted
+		{"0 0 1 * 0", "2016-12-23T17:00:00Z", "2016-12-25T00:00:00Z"},
+		{"0 8-10/2 * * 1-5", "2016-12-23T09:00:00Z", "2016-12-23T10:00:00Z"},
+		{"0 0 * * 7", "2016-12-23T17:00:00Z", "2016-12-25T00:00:00Z"},
+	}
+	for _, c := range cases {
+		schedule, err := parseCron(c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next := schedule.next(*fakeTime(c.from)); !next.Equal(*fakeTime(c.want)) {
+			t.Errorf("next of %q from %s is %v, want %s", c.expr, c.from, next, c.want)
+		}
+	}
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("%q should be invalid", expr)
+		}
+	}
+	if next := (&cronSchedule{}).next(time.Now()); !next.IsZero() {
+		t.Errorf("schedule matching nothing should never come, got %v", next)
+	}
+}
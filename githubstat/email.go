@@ -0,0 +1,193 @@
+package githubstat
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EmailTLSNone     = "none"     // plain connection
+	EmailTLSStartTLS = "starttls" // plain connection upgraded by STARTTLS, usually on port 587
+	EmailTLSImplicit = "tls"      // TLS connection from the start, usually on port 465
+
+	DefaultEmailSubject = "github contrib stats"
+)
+
+type EmailConfig struct {
+	Host     string // SMTP server, email is not sent if it is empty
+	Port     int    // defaults to the usual port of TLS
+	TLS      string // "starttls" (default), "tls" or "none"
+	Username string // authenticated by PLAIN if it is not empty
+	Password string
+	From     string
+	To       []string
+	Subject  string
+}
+
+// defaultEmailPort returns the usual port of SMTP servers by tls, 0 if tls is unknown.
+func defaultEmailPort(tls string) int {
+	switch tls {
+	case "", EmailTLSStartTLS:
+		return 587
+	case EmailTLSImplicit:
+		return 465
+	case EmailTLSNone:
+		return 25
+	}
+	return 0
+}
+
+// emailSections returns sections of every window followed by sections of every team in the window.
+func emailSections(sections []*reportSection) []*reportSection {
+	var withTeams []*reportSection
+	for _, section := range sections {
+		withTeams = append(withTeams, section)
+		for _, team := range Config.Teams {
+			var metrics []*PullRequestMetrics
+			for _, m := range section.Metrics {
+				if team.hasMember(m.User) {
+					metrics = append(metrics, m)
+				}
+			}
+			withTeams = append(withTeams, &reportSection{Title: section.Title + " : " + team.Name, Metrics: metrics})
+		}
+	}
+	return withTeams
+}
+
+var emailTemplate = template.Must(template.New("email").Funcs(template.FuncMap{
+	"displayName":    userDisplayName,
+	"acceptanceRate": acceptanceRate,
+	"total":          total,
+}).Parse(`<html>
+<body style="font-family: sans-serif;">
+{{range .}}
+<h3>{{.Title}}</h3>
+<table style="border-collapse: collapse;" border="1" cellpadding="4">
+<tr><th>User Name</th><th>Merged PRs</th><th>Merged Commits</th><th>LGTM'ed PRs</th><th>NonLGTM'ed PRs</th><th>Created PRs</th><th>Closed Unmerged PRs</th><th>Acceptance Rate</th></tr>
+{{range .Metrics}}<tr><td>{{displayName .User}}</td><td align="right">{{.Merged}}</td><td align="right">{{.MergedCommits}}</td><td align="right">{{.LGTMed}}</td><td align="right">{{.NonLGTMed}}</td><td align="right">{{.Created}}</td><td align="right">{{.ClosedUnmerged}}</td><td align="right">{{acceptanceRate .Merged .ClosedUnmerged}}</td></tr>
+{{end}}{{with total .Metrics}}<tr><th align="left">Total</th><th align="right">{{.Merged}}</th><th align="right">{{.MergedCommits}}</th><th align="right">{{.LGTMed}}</th><th align="right">{{.NonLGTMed}}</th><th align="right">{{.Created}}</th><th align="right">{{.ClosedUnmerged}}</th><th align="right">{{acceptanceRate .Merged .ClosedUnmerged}}</th></tr>{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+// newEmailMessage renders sections as an HTML email with a plain text alternative.
+func newEmailMessage(cfg *EmailConfig, sections []*reportSection, date time.Time) ([]byte, error) {
+	var text bytes.Buffer
+	for _, section := range sections {
+		fmt.Fprintf(&text, "%s\n", section.Title)
+		writeMetricsTable(&text, section.Metrics, nil)
+		text.WriteString("\n")
+	}
+	var html bytes.Buffer
+	if err := emailTemplate.Execute(&html, sections); err != nil {
+		return nil, err
+	}
+
+	subject := cfg.Subject
+	if subject == "" {
+		subject = DefaultEmailSubject
+	}
+	var msg bytes.Buffer
+	body := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", body.Boundary())
+	// the last part is preferred by mail clients
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{{"text/plain; charset=utf-8", text.Bytes()}, {"text/html; charset=utf-8", html.Bytes()}} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+// sendEmail sends the message to recipients through the SMTP server.
+func sendEmail(cfg *EmailConfig, msg []byte) error {
+	if len(cfg.To) == 0 {
+		return fmt.Errorf("recipients of email are not configured")
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+	var conn net.Conn
+	var err error
+	switch cfg.TLS {
+	case EmailTLSImplicit:
+		conn, err = tls.Dial("tcp", addr, tlsConfig)
+	case "", EmailTLSStartTLS, EmailTLSNone:
+		conn, err = net.Dial("tcp", addr)
+	default:
+		return fmt.Errorf("unknown tls of email : %s, must be %q, %q or %q", cfg.TLS, EmailTLSStartTLS, EmailTLSImplicit, EmailTLSNone)
+	}
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if cfg.TLS == "" || cfg.TLS == EmailTLSStartTLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, to := range cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,134 @@
+package githubstat
+
+import (
+	"bufio"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smtpSink is a local SMTP server accepting any message without TLS and authentication.
+type smtpSink struct {
+	net.Listener
+	recipients []string
+	messages   chan string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpSink{Listener: l, messages: make(chan string, 1)}
+	go s.serve()
+	return s
+}
+
+func (s *smtpSink) serve() {
+	conn, err := s.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 sink")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 sink")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.recipients = append(s.recipients, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.messages <- data.String()
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func Test_sendEmail(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "", "2016-12-24T00:00:00Z")()
+	teams := Config.Teams
+	defer func() { Config.Teams = teams }()
+	Config.Teams = []Team{{Name: "docs", Members: []string{"tanshanshan"}}}
+	sink := newSMTPSink(t)
+	defer sink.Close()
+
+	host, port, _ := net.SplitHostPort(sink.Addr().String())
+	cfg := &EmailConfig{Host: host, TLS: EmailTLSNone, From: "stats@example.com", To: []string{"a@example.com", "b@example.com"},
+		Subject: "周报"}
+	cfg.Port, _ = strconv.Atoi(port)
+	sections := emailSections(reportSections(newTestServer().fetch(nil, Config.ThisWeekFirstDay), *fakeTime("2016-12-25T00:00:00Z"), Config.ThisWeekFirstDay))
+	if len(sections) != 4 || sections[1].Title != sections[0].Title+" : docs" || len(sections[1].Metrics) != 1 {
+		t.Fatalf("sections are %+v, want week, week of docs, overall and overall of docs", sections)
+	}
+	msg, err := newEmailMessage(cfg, sections, *fakeTime("2016-12-25T00:00:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sendEmail(cfg, msg); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(sink.recipients, ",") != "a@example.com,b@example.com" {
+		t.Errorf("recipients are %v", sink.recipients)
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(<-sink.messages))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject")); subject != "周报" {
+		t.Errorf("subject is %q", subject)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type is %s", m.Header.Get("Content-Type"))
+	}
+	parts := multipart.NewReader(m.Body, params["boundary"])
+	for _, want := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", "Overall Statistics ( 2016-10-01 ~ 2016-12-25) : docs"},
+		{"text/html; charset=utf-8", "<td>bruceauyeung</td><td align=\"right\">5</td>"},
+	} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadAll(part)
+		if part.Header.Get("Content-Type") != want.contentType || !strings.Contains(string(content), want.content) {
+			t.Errorf("%s part does not contain %q:\n%s", part.Header.Get("Content-Type"), want.content, content)
+		}
+	}
+}
+
+func Test_defaultEmailPort(t *testing.T) {
+	for tls, want := range map[string]int{"": 587, EmailTLSStartTLS: 587, EmailTLSImplicit: 465, EmailTLSNone: 25, "ssl": 0} {
+		if port := defaultEmailPort(tls); port != want {
+			t.Errorf("default port of tls %q is %d, want %d", tls, port, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package githubstat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+// fakeGitea is a fake Gitea API server which serves PRs of one repo and repos of organizations and users
+// from memory, lists are paged by perPage items with Link headers.
+type fakeGitea struct {
+	*httptest.Server
+	t            *testing.T
+	perPage      int
+	pullRequests []*giteaPullRequest
+	commits      map[int][]*github.RepositoryCommit
+	timeline     map[int][]*giteaTimelineEvent
+	reviews      map[int][]*giteaReview
+	// repos by organization or user, owners in neither of them are not found, "broken" organizations fail
+	orgs  map[string][]*github.Repository
+	users map[string][]*github.Repository
+	// paths and queries of requests served
+	requests []string
+}
+
+var (
+	fakeGiteaPullsPath = regexp.MustCompile(`^/api/v1/repos/[^/]+/[^/]+/pulls$`)
+	fakeGiteaPullPath  = regexp.MustCompile(`^/api/v1/repos/[^/]+/[^/]+/(pulls|issues)/(\d+)(/[a-z]+)?$`)
+	fakeGiteaReposPath = regexp.MustCompile(`^/api/v1/(orgs|users)/([^/]+)/repos$`)
+)
+
+func newFakeGitea(t *testing.T) *fakeGitea {
+	f := &fakeGitea{
+		t:        t,
+		perPage:  2,
+		commits:  make(map[int][]*github.RepositoryCommit),
+		timeline: make(map[int][]*giteaTimelineEvent),
+		reviews:  make(map[int][]*giteaReview),
+		orgs:     make(map[string][]*github.Repository),
+		users:    make(map[string][]*github.Repository),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+// provider returns a Gitea provider which requests the fake server.
+func (f *fakeGitea) provider() *giteaProvider {
+	return &giteaProvider{
+		httpClient: http.DefaultClient,
+		baseURL:    f.URL + "/api/v1/",
+		cache:      make(map[string]*giteaPullRequest),
+		closed:     make(map[string][]*github.PullRequest),
+		commits:    make(map[string][]*github.RepositoryCommit),
+	}
+}
+
+func (f *fakeGitea) addPullRequest(number int, login string, state string, createdAt, updatedAt, mergedAt string) *giteaPullRequest {
+	pr := &giteaPullRequest{}
+	title := "PR " + strconv.Itoa(number)
+	pr.Number = &number
+	pr.Title = &title
+	pr.State = &state
+	pr.User = &github.User{Login: &login}
+	pr.CreatedAt = fakeTime(createdAt)
+	pr.UpdatedAt = fakeTime(updatedAt)
+	if state == "closed" {
+		pr.ClosedAt = fakeTime(updatedAt)
+		pr.MergedAt = fakeTime(mergedAt)
+	}
+	f.pullRequests = append(f.pullRequests, pr)
+	return pr
+}
+
+func (f *fakeGitea) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	path := r.URL.Path
+	query := r.URL.Query()
+	f.requests = append(f.requests, r.URL.String())
+	switch {
+	case fakeGiteaPullsPath.MatchString(path):
+		var prs []*giteaPullRequest
+		for _, pr := range f.pullRequests {
+			if query.Get("state") == "all" || *pr.State == query.Get("state") {
+				prs = append(prs, pr)
+			}
+		}
+		sort.Slice(prs, func(i, j int) bool {
+			if query.Get("sort") == "recentupdate" {
+				return prs[i].UpdatedAt.After(*prs[j].UpdatedAt)
+			}
+			return prs[i].CreatedAt.After(*prs[j].CreatedAt)
+		})
+		begin, end := f.page(w, r, len(prs))
+		f.write(w, prs[begin:end])
+	case fakeGiteaPullPath.MatchString(path):
+		m := fakeGiteaPullPath.FindStringSubmatch(path)
+		number, _ := strconv.Atoi(m[2])
+		var items []interface{}
+		switch m[1] + m[3] {
+		case "pulls":
+			for _, pr := range f.pullRequests {
+				if *pr.Number == number {
+					f.write(w, pr)
+					return
+				}
+			}
+			http.NotFound(w, r)
+			return
+		case "pulls/commits":
+			for _, c := range f.commits[number] {
+				items = append(items, c)
+			}
+		case "pulls/reviews":
+			for _, review := range f.reviews[number] {
+				items = append(items, review)
+			}
+		case "issues/timeline":
+			for _, e := range f.timeline[number] {
+				items = append(items, e)
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		begin, end := f.page(w, r, len(items))
+		f.write(w, append([]interface{}{}, items[begin:end]...))
+	case fakeGiteaReposPath.MatchString(path):
+		m := fakeGiteaReposPath.FindStringSubmatch(path)
+		if m[2] == "broken" {
+			http.Error(w, "{}", http.StatusInternalServerError)
+			return
+		}
+		repos, found := f.orgs[m[2]]
+		if m[1] == "users" {
+			repos, found = f.users[m[2]]
+		}
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		begin, end := f.page(w, r, len(repos))
+		f.write(w, repos[begin:end])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// page returns bounds of the requested page of n items, and sets the Link header of the next page if there is one.
+func (f *fakeGitea) page(w http.ResponseWriter, r *http.Request, n int) (int, int) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	begin := (page - 1) * f.perPage
+	if begin > n {
+		begin = n
+	}
+	end := begin + f.perPage
+	if end >= n {
+		return begin, n
+	}
+	u := *r.URL
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page+1))
+	u.RawQuery = query.Encode()
+	w.Header().Set("Link", `<`+f.URL+u.String()+`>; rel="next"`)
+	return begin, end
+}
+
+func (f *fakeGitea) write(w http.ResponseWriter, v interface{}) {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		f.t.Errorf("failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,403 @@
+package githubstat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// fakeGitHub is a fake GitHub API server which serves PRs, issues, issue events and commits from memory,
+// lists are paged by perPage items so that paging of clients is tested too.
+type fakeGitHub struct {
+	*httptest.Server
+	t            *testing.T
+	perPage      int
+	pullRequests []*github.PullRequest
+	labels       map[int][]string
+	events       map[int][]*github.IssueEvent
+	closedBy     map[int]string
+	approvals    map[int][]string // times of approved reviews
+	commits      []*github.RepositoryCommit
+	// whether the last page is left out of Link headers, like GitHub does for some large lists
+	noLastPage bool
+	// PR numbers by SHA of their commits, which are searched by findPullRequest, commits pushed directly have no PR
+	commitPullRequests map[string]int
+	// paths of requests served
+	requests []string
+	// variables of GraphQL queries served
+	graphqlVariables []map[string]interface{}
+}
+
+var (
+	fakePullRequestPath  = regexp.MustCompile(`^/repos/[^/]+/[^/]+/pulls/(\d+)$`)
+	fakeIssuePath        = regexp.MustCompile(`^/repos/[^/]+/[^/]+/issues/(\d+)$`)
+	fakeIssueEventsPath  = regexp.MustCompile(`^/repos/[^/]+/[^/]+/issues/(\d+)/events$`)
+	fakePullRequestsPath = regexp.MustCompile(`^/repos/[^/]+/[^/]+/pulls$`)
+	fakeCommitsPath      = regexp.MustCompile(`^/repos/[^/]+/[^/]+/commits$`)
+)
+
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	f := &fakeGitHub{
+		t:                  t,
+		perPage:            2,
+		labels:             make(map[int][]string),
+		events:             make(map[int][]*github.IssueEvent),
+		closedBy:           make(map[int]string),
+		approvals:          make(map[int][]string),
+		commitPullRequests: make(map[string]int),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+// client returns a github client which requests the fake server.
+func (f *fakeGitHub) client() *github.Client {
+	client := github.NewClient(nil)
+	client.BaseURL = mustParseURL(f.URL + "/")
+	return client
+}
+
+func (f *fakeGitHub) addPullRequest(number int, login string, state string, createdAt, updatedAt, mergedAt string) *github.PullRequest {
+	title := "PR " + strconv.Itoa(number)
+	pr := &github.PullRequest{
+		Number:    &number,
+		Title:     &title,
+		State:     &state,
+		User:      &github.User{Login: &login},
+		CreatedAt: fakeTime(createdAt),
+		UpdatedAt: fakeTime(updatedAt),
+	}
+	if state == "closed" {
+		pr.ClosedAt = fakeTime(updatedAt)
+		pr.MergedAt = fakeTime(mergedAt)
+	}
+	f.pullRequests = append(f.pullRequests, pr)
+	return pr
+}
+
+func (f *fakeGitHub) addLabel(number int, label string, at string) {
+	f.labels[number] = append(f.labels[number], label)
+	labeled := "labeled"
+	f.events[number] = append(f.events[number], &github.IssueEvent{
+		Event:     &labeled,
+		CreatedAt: fakeTime(at),
+		Label:     &github.Label{Name: &label},
+	})
+}
+
+func (f *fakeGitHub) addCommit(sha string, login string, message string, number int) {
+	f.commits = append(f.commits, &github.RepositoryCommit{
+		SHA:    &sha,
+		Author: &github.User{Login: &login},
+		Commit: &github.Commit{Message: &message},
+	})
+	if number != 0 {
+		f.commitPullRequests[sha] = number
+	}
+}
+
+func (f *fakeGitHub) findPullRequest(number int) *github.PullRequest {
+	for _, pr := range f.pullRequests {
+		if *pr.Number == number {
+			return pr
+		}
+	}
+	return nil
+}
+
+// fakeTime parses "2006-01-02T15:04:05Z", empty string is nil.
+func fakeTime(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func (f *fakeGitHub) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-RateLimit-Limit", "5000")
+	w.Header().Set("X-RateLimit-Remaining", "4999")
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+
+	path := r.URL.Path
+	query := r.URL.Query()
+	f.requests = append(f.requests, path)
+	switch {
+	case fakePullRequestsPath.MatchString(path):
+		var prs []*github.PullRequest
+		for _, pr := range f.pullRequests {
+			if query.Get("state") == "all" || *pr.State == query.Get("state") {
+				prs = append(prs, pr)
+			}
+		}
+		sort.Slice(prs, func(i, j int) bool {
+			if query.Get("sort") == "updated" {
+				return prs[i].UpdatedAt.After(*prs[j].UpdatedAt)
+			}
+			return prs[i].CreatedAt.After(*prs[j].CreatedAt)
+		})
+		begin, end := f.page(w, r, len(prs))
+		f.write(w, prs[begin:end])
+	case fakePullRequestPath.MatchString(path):
+		number, _ := strconv.Atoi(fakePullRequestPath.FindStringSubmatch(path)[1])
+		pr := f.findPullRequest(number)
+		if pr == nil {
+			http.NotFound(w, r)
+			return
+		}
+		f.write(w, pr)
+	case fakeIssuePath.MatchString(path):
+		number, _ := strconv.Atoi(fakeIssuePath.FindStringSubmatch(path)[1])
+		issue := &github.Issue{Number: &number}
+		if login, found := f.closedBy[number]; found {
+			issue.ClosedBy = &github.User{Login: &login}
+		}
+		for _, l := range f.labels[number] {
+			name := l
+			issue.Labels = append(issue.Labels, github.Label{Name: &name})
+		}
+		f.write(w, issue)
+	case fakeIssueEventsPath.MatchString(path):
+		number, _ := strconv.Atoi(fakeIssueEventsPath.FindStringSubmatch(path)[1])
+		events := f.events[number]
+		begin, end := f.page(w, r, len(events))
+		f.write(w, events[begin:end])
+	case fakeCommitsPath.MatchString(path):
+		var commits []*github.RepositoryCommit
+		for _, c := range f.commits {
+			if query.Get("author") == "" || *c.Author.Login == query.Get("author") {
+				commits = append(commits, c)
+			}
+		}
+		begin, end := f.page(w, r, len(commits))
+		f.write(w, commits[begin:end])
+	case path == "/graphql":
+		f.serveGraphQL(w, r)
+	case path == "/rate_limit":
+		reset := time.Now().Add(time.Hour).Unix()
+		f.write(w, map[string]interface{}{"resources": map[string]interface{}{
+			"core":   map[string]int64{"limit": 5000, "remaining": 4999, "reset": reset},
+			"search": map[string]int64{"limit": 30, "remaining": 30, "reset": reset},
+		}})
+	case path == "/search/issues":
+		// query is "SHA repo:owner/repo type:pr author:login"
+		result := &github.IssuesSearchResult{Total: new(int)}
+		if number, found := f.commitPullRequests[strings.Fields(query.Get("q"))[0]]; found {
+			result.Issues = append(result.Issues, github.Issue{Number: &number})
+			*result.Total = 1
+		}
+		f.write(w, result)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// graphqlState returns state of a PR in GraphQL API, which tells merged PRs from closed ones.
+func graphqlState(pr *github.PullRequest) string {
+	switch {
+	case *pr.State == "open":
+		return "OPEN"
+	case pr.MergedAt != nil:
+		return "MERGED"
+	}
+	return "CLOSED"
+}
+
+// graphqlConnection returns the page of nodes after the cursor of a connection, nodes are paged by perPage items
+// with cursors of indexes.
+func (f *fakeGitHub) graphqlConnection(nodes []map[string]interface{}, cursor string) map[string]interface{} {
+	begin, _ := strconv.Atoi(cursor)
+	if begin > len(nodes) {
+		begin = len(nodes)
+	}
+	end := begin + f.perPage
+	if end > len(nodes) {
+		end = len(nodes)
+	}
+	return map[string]interface{}{
+		"pageInfo": map[string]interface{}{"hasNextPage": end < len(nodes), "endCursor": strconv.Itoa(end)},
+		"nodes":    nodes[begin:end],
+	}
+}
+
+// graphqlPullRequest returns fields of pullRequestFields of a PR, connections are paged after their cursors.
+func (f *fakeGitHub) graphqlPullRequest(owner string, repo string, pr *github.PullRequest, cursors map[string]string) map[string]interface{} {
+	number := *pr.Number
+	intOf := func(i *int) int {
+		if i == nil {
+			return 0
+		}
+		return *i
+	}
+	var labels, timeline, reviews []map[string]interface{}
+	for _, l := range f.labels[number] {
+		labels = append(labels, map[string]interface{}{"name": l})
+	}
+	for _, e := range f.events[number] {
+		timeline = append(timeline, map[string]interface{}{"__typename": "LabeledEvent", "createdAt": e.CreatedAt,
+			"label": map[string]interface{}{"name": *e.Label.Name}})
+	}
+	if pr.ClosedAt != nil {
+		closed := map[string]interface{}{"__typename": "ClosedEvent", "createdAt": pr.ClosedAt}
+		if login, found := f.closedBy[number]; found {
+			closed["actor"] = map[string]interface{}{"login": login}
+		}
+		timeline = append(timeline, closed)
+	}
+	for _, at := range f.approvals[number] {
+		reviews = append(reviews, map[string]interface{}{"submittedAt": fakeTime(at), "author": map[string]interface{}{"login": "reviewer"}})
+	}
+	return map[string]interface{}{
+		"number":        number,
+		"title":         *pr.Title,
+		"url":           "https://github.com/" + owner + "/" + repo + "/pull/" + strconv.Itoa(number),
+		"state":         graphqlState(pr),
+		"createdAt":     pr.CreatedAt,
+		"updatedAt":     pr.UpdatedAt,
+		"closedAt":      pr.ClosedAt,
+		"mergedAt":      pr.MergedAt,
+		"additions":     intOf(pr.Additions),
+		"deletions":     intOf(pr.Deletions),
+		"changedFiles":  intOf(pr.ChangedFiles),
+		"author":        map[string]interface{}{"login": *pr.User.Login},
+		"commits":       map[string]interface{}{"totalCount": intOf(pr.Commits)},
+		"labels":        f.graphqlConnection(labels, cursors["labels"]),
+		"timelineItems": f.graphqlConnection(timeline, cursors["timelineItems"]),
+		"reviews":       f.graphqlConnection(reviews, cursors["reviews"]),
+	}
+}
+
+// serveGraphQL serves queries of graphqlProvider, PRs are paged by perPage items with cursors of indexes.
+func (f *fakeGitHub) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		f.t.Errorf("failed to decode graphql query: %v", err)
+	}
+	f.graphqlVariables = append(f.graphqlVariables, body.Variables)
+	owner, _ := body.Variables["owner"].(string)
+	repo, _ := body.Variables["name"].(string)
+	data := map[string]interface{}{"rateLimit": map[string]interface{}{"limit": 5000, "remaining": 4999,
+		"resetAt": time.Now().Add(time.Hour)}}
+	switch {
+	case strings.Contains(body.Query, "files("):
+		data["repository"] = map[string]interface{}{"pullRequest": map[string]interface{}{"files": map[string]interface{}{
+			"pageInfo": map[string]interface{}{"hasNextPage": false}, "nodes": []interface{}{}}}}
+	case strings.Contains(body.Query, "pullRequests("):
+		states := make(map[string]bool)
+		for _, s := range body.Variables["states"].([]interface{}) {
+			states[s.(string)] = true
+		}
+		var prs []*github.PullRequest
+		for _, pr := range f.pullRequests {
+			if states[graphqlState(pr)] {
+				prs = append(prs, pr)
+			}
+		}
+		sort.Slice(prs, func(i, j int) bool {
+			if body.Variables["field"] == "UPDATED_AT" {
+				return prs[i].UpdatedAt.After(*prs[j].UpdatedAt)
+			}
+			return prs[i].CreatedAt.After(*prs[j].CreatedAt)
+		})
+		begin := 0
+		if cursor, found := body.Variables["cursor"].(string); found {
+			begin, _ = strconv.Atoi(cursor)
+		}
+		end := begin + f.perPage
+		if end > len(prs) {
+			end = len(prs)
+		}
+		var nodes []map[string]interface{}
+		for _, pr := range prs[begin:end] {
+			nodes = append(nodes, f.graphqlPullRequest(owner, repo, pr, nil))
+		}
+		data["repository"] = map[string]interface{}{"pullRequests": map[string]interface{}{
+			"pageInfo": map[string]interface{}{"hasNextPage": end < len(prs), "endCursor": strconv.Itoa(end)},
+			"nodes":    nodes,
+		}}
+	case strings.Contains(body.Query, "pullRequest(number"):
+		number, _ := body.Variables["number"].(float64)
+		// the cursor is of the connection queried after it
+		cursors := make(map[string]string)
+		for _, connection := range []string{"labels", "timelineItems", "reviews"} {
+			if strings.Contains(body.Query, connection+"(first: 100, after: $cursor") {
+				cursors[connection], _ = body.Variables["cursor"].(string)
+			}
+		}
+		var node interface{}
+		if pr := f.findPullRequest(int(number)); pr != nil {
+			node = f.graphqlPullRequest(owner, repo, pr, cursors)
+		}
+		data["repository"] = map[string]interface{}{"pullRequest": node}
+	default:
+		f.write(w, map[string]interface{}{"errors": []interface{}{map[string]string{"message": "unknown query"}}})
+		return
+	}
+	f.write(w, map[string]interface{}{"data": data})
+}
+
+// page returns bounds of the requested page of n items, and sets the Link header of the next and the last page
+// if there is a next page.
+func (f *fakeGitHub) page(w http.ResponseWriter, r *http.Request, n int) (int, int) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	begin := (page - 1) * f.perPage
+	if begin > n {
+		begin = n
+	}
+	end := begin + f.perPage
+	if end >= n {
+		return begin, n
+	}
+	link := func(page int, rel string) string {
+		u := *r.URL
+		query := u.Query()
+		query.Set("page", strconv.Itoa(page))
+		u.RawQuery = query.Encode()
+		return `<` + f.URL + u.String() + `>; rel="` + rel + `"`
+	}
+	if f.noLastPage {
+		w.Header().Set("Link", link(page+1, "next"))
+	} else {
+		w.Header().Set("Link", link(page+1, "next")+", "+link((n+f.perPage-1)/f.perPage, "last"))
+	}
+	return begin, end
+}
+
+func (f *fakeGitHub) write(w http.ResponseWriter, v interface{}) {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		f.t.Errorf("failed to encode response: %v", err)
+	}
+}
+
+// setStatPeriod sets stat period and first day of this week, and returns a function restoring them.
+func setStatPeriod(begin, end, thisWeek string) func() {
+	statBeginTime, statEndTime, thisWeekFirstDay := Config.StatBeginTime, Config.StatEndTime, Config.ThisWeekFirstDay
+	restore := func() {
+		Config.StatBeginTime, Config.StatEndTime, Config.ThisWeekFirstDay = statBeginTime, statEndTime, thisWeekFirstDay
+	}
+	Config.StatBeginTime = *fakeTime(begin)
+	Config.StatEndTime = time.Time{}
+	if end != "" {
+		Config.StatEndTime = *fakeTime(end)
+	}
+	Config.ThisWeekFirstDay = *fakeTime(thisWeek)
+	return restore
+}
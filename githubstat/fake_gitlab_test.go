@@ -0,0 +1,196 @@
+package githubstat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+// fakeGitLab is a fake GitLab API server which serves merge requests of one project and projects of groups
+// and users from memory, lists are paged by perPage items with X-Next-Page headers.
+type fakeGitLab struct {
+	*httptest.Server
+	t             *testing.T
+	perPage       int
+	mergeRequests []*gitlabMergeRequest
+	commits       map[int][]*gitlabCommit
+	notes         map[int][]*gitlabNote
+	labelEvents   map[int][]*gitlabLabelEvent
+	diffs         map[int][]*gitlabDiff
+	// projects by group or user, owners in neither of them are not found, "broken" groups fail
+	groups map[string][]*gitlabProject
+	users  map[string][]*gitlabProject
+	// paths of requests served
+	requests []string
+}
+
+var (
+	fakeGitLabMergeRequestsPath = regexp.MustCompile(`^/api/v4/projects/[^/]+/merge_requests$`)
+	fakeGitLabMergeRequestPath  = regexp.MustCompile(`^/api/v4/projects/[^/]+/merge_requests/(\d+)(/[a-z_]+)?$`)
+	fakeGitLabProjectsPath      = regexp.MustCompile(`^/api/v4/(groups|users)/([^/]+)/projects$`)
+)
+
+func newFakeGitLab(t *testing.T) *fakeGitLab {
+	f := &fakeGitLab{
+		t:           t,
+		perPage:     2,
+		commits:     make(map[int][]*gitlabCommit),
+		notes:       make(map[int][]*gitlabNote),
+		labelEvents: make(map[int][]*gitlabLabelEvent),
+		diffs:       make(map[int][]*gitlabDiff),
+		groups:      make(map[string][]*gitlabProject),
+		users:       make(map[string][]*gitlabProject),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+// provider returns a GitLab provider which requests the fake server.
+func (f *fakeGitLab) provider() *gitlabProvider {
+	return &gitlabProvider{
+		httpClient: http.DefaultClient,
+		baseURL:    f.URL + "/api/v4/",
+		cache:      make(map[string]*gitlabMergeRequest),
+		files:      make(map[string][]*github.CommitFile),
+		merged:     make(map[string][]*gitlabMergeRequest),
+		commits:    make(map[string][]*gitlabCommit),
+	}
+}
+
+// addMergeRequest adds a merge request, updatedAt is also the time it was merged or closed.
+func (f *fakeGitLab) addMergeRequest(iid int, username string, state string, createdAt, updatedAt string, labels ...string) *gitlabMergeRequest {
+	mr := &gitlabMergeRequest{
+		IID:       iid,
+		Title:     "MR " + strconv.Itoa(iid),
+		WebURL:    "https://gitlab.example.com/kubernetes/kubernetes/-/merge_requests/" + strconv.Itoa(iid),
+		State:     state,
+		CreatedAt: *fakeTime(createdAt),
+		UpdatedAt: *fakeTime(updatedAt),
+		Author:    &gitlabUser{Username: username},
+		Labels:    labels,
+	}
+	switch state {
+	case "merged":
+		mr.MergedAt = fakeTime(updatedAt)
+	case "closed":
+		mr.ClosedAt = fakeTime(updatedAt)
+	}
+	f.mergeRequests = append(f.mergeRequests, mr)
+	return mr
+}
+
+func (f *fakeGitLab) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// project paths are escaped, e.g. "projects/kubernetes%2Fkubernetes"
+	path := r.URL.EscapedPath()
+	query := r.URL.Query()
+	f.requests = append(f.requests, path)
+	switch {
+	case fakeGitLabMergeRequestsPath.MatchString(path):
+		var mrs []*gitlabMergeRequest
+		for _, mr := range f.mergeRequests {
+			if state := query.Get("state"); state != "all" && mr.State != state {
+				continue
+			}
+			if author := query.Get("author_username"); author != "" && mr.Author.Username != author {
+				continue
+			}
+			if after := query.Get("updated_after"); after != "" && mr.UpdatedAt.Before(*fakeTime(after)) {
+				continue
+			}
+			mrs = append(mrs, mr)
+		}
+		sort.Slice(mrs, func(i, j int) bool {
+			if query.Get("order_by") == "updated_at" {
+				return mrs[i].UpdatedAt.After(mrs[j].UpdatedAt)
+			}
+			return mrs[i].CreatedAt.After(mrs[j].CreatedAt)
+		})
+		begin, end := f.page(w, r, len(mrs))
+		f.write(w, mrs[begin:end])
+	case fakeGitLabMergeRequestPath.MatchString(path):
+		m := fakeGitLabMergeRequestPath.FindStringSubmatch(path)
+		iid, _ := strconv.Atoi(m[1])
+		var items []interface{}
+		switch m[2] {
+		case "":
+			for _, mr := range f.mergeRequests {
+				if mr.IID == iid {
+					f.write(w, mr)
+					return
+				}
+			}
+			http.NotFound(w, r)
+			return
+		case "/commits":
+			for _, c := range f.commits[iid] {
+				items = append(items, c)
+			}
+		case "/notes":
+			for _, n := range f.notes[iid] {
+				items = append(items, n)
+			}
+		case "/resource_label_events":
+			for _, e := range f.labelEvents[iid] {
+				items = append(items, e)
+			}
+		case "/diffs":
+			for _, d := range f.diffs[iid] {
+				items = append(items, d)
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		begin, end := f.page(w, r, len(items))
+		f.write(w, append([]interface{}{}, items[begin:end]...))
+	case fakeGitLabProjectsPath.MatchString(path):
+		m := fakeGitLabProjectsPath.FindStringSubmatch(path)
+		if m[2] == "broken" {
+			http.Error(w, "{}", http.StatusInternalServerError)
+			return
+		}
+		projects, found := f.groups[m[2]]
+		if m[1] == "users" {
+			projects, found = f.users[m[2]]
+		}
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		begin, end := f.page(w, r, len(projects))
+		f.write(w, projects[begin:end])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// page returns bounds of the requested page of n items, and sets X-Next-Page header if there is a next page.
+func (f *fakeGitLab) page(w http.ResponseWriter, r *http.Request, n int) (int, int) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	begin := (page - 1) * f.perPage
+	if begin > n {
+		begin = n
+	}
+	end := begin + f.perPage
+	if end >= n {
+		return begin, n
+	}
+	w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+	return begin, end
+}
+
+func (f *fakeGitLab) write(w http.ResponseWriter, v interface{}) {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		f.t.Errorf("failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package githubstat
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FixtureModeRecord = "record"
+	FixtureModeReplay = "replay"
+	DefaultFixtureDir = "fixtures"
+)
+
+// fixture is an API interaction saved in a file.
+type fixture struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// fixtureTransport saves API interactions to fixture files in record mode, and serves requests
+// from fixture files without network in replay mode. requests are matched by method, url and body.
+type fixtureTransport struct {
+	mode      string
+	dir       string
+	transport http.RoundTripper
+}
+
+func newFixtureTransport(mode string, dir string, transport http.RoundTripper) *fixtureTransport {
+	return &fixtureTransport{mode: mode, dir: dir, transport: transport}
+}
+
+// fixturePath returns path of the fixture file of a request, e.g. "get_repos_kubernetes_kubernetes_pulls_1a2b3c4d.json".
+func (t *fixtureTransport) fixturePath(req *http.Request, body []byte) string {
+	sum := sha1.Sum(append([]byte(req.Method+" "+req.URL.String()+"\n"), body...))
+	name := strings.Trim(strings.Replace(req.URL.Path, "/", "_", -1), "_")
+	return filepath.Join(t.dir, fmt.Sprintf("%s_%s_%x.json", strings.ToLower(req.Method), name, sum[:4]))
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	path := t.fixturePath(req, body)
+	if t.mode == FixtureModeReplay {
+		return t.replay(req, path)
+	}
+	return t.record(req, path)
+}
+
+func (t *fixtureTransport) record(req *http.Request, path string) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	data, err := json.MarshalIndent(&fixture{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       string(body),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (t *fixtureTransport) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no fixture of %s %s : %v", req.Method, req.URL, err)
+	}
+	f := &fixture{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s : %v", path, err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.StatusCode, http.StatusText(f.StatusCode)),
+		StatusCode:    f.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Header,
+		Body:          ioutil.NopCloser(strings.NewReader(f.Body)),
+		ContentLength: int64(len(f.Body)),
+		Request:       req,
+	}, nil
+}
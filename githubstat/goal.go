@@ -0,0 +1,232 @@
+package githubstat
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	GoalMetricMerged  = "merged"
+	GoalMetricCommits = "commits"
+	GoalMetricLGTMed  = "lgtmed"
+	GoalMetricCreated = "created"
+
+	// goals are gated by achieved values, or by values projected to the end of the stat period
+	GoalGateAchieved  = "achieved"
+	GoalGateProjected = "projected"
+
+	GoalStatusMet     = "met"
+	GoalStatusOnTrack = "on track"
+	GoalStatusBehind  = "behind"
+)
+
+// goalMetricTitles are titles of goal metrics in headers of metrics tables.
+var goalMetricTitles = map[string]string{
+	GoalMetricMerged:  "Merged PRs",
+	GoalMetricCommits: "Merged Commits",
+	GoalMetricLGTMed:  "LGTM'ed PRs",
+	GoalMetricCreated: "Created PRs",
+}
+
+// Goal is the target of a metric in the stat period, of a user, of every member of a team,
+// or of every user by default if neither user nor team is specified.
+type Goal struct {
+	User   string
+	Team   string
+	Metric string // "merged", "commits", "lgtmed" or "created"
+	Target int
+}
+
+// GoalProgress is how far a user has got to a goal.
+type GoalProgress struct {
+	User      string
+	Metric    string
+	Achieved  int
+	Target    int
+	Projected int // value at the end of the stat period at the current pace, -1 if the stat period has no end
+	Status    string
+}
+
+// Complete is the percentage of the target achieved.
+func (p *GoalProgress) Complete() float64 {
+	if p.Target <= 0 {
+		return 100
+	}
+	return float64(p.Achieved) * 100 / float64(p.Target)
+}
+
+// failed reports whether the goal fails the gate.
+func (p *GoalProgress) failed(gate string) bool {
+	if gate == GoalGateProjected {
+		return p.Projected < p.Target
+	}
+	return p.Achieved < p.Target
+}
+
+func goalMetricValue(metric string, m *PullRequestMetrics) (int, error) {
+	switch metric {
+	case GoalMetricMerged:
+		return m.Merged, nil
+	case GoalMetricCommits:
+		return m.MergedCommits, nil
+	case GoalMetricLGTMed:
+		return m.LGTMed, nil
+	case GoalMetricCreated:
+		return m.Created, nil
+	}
+	return 0, fmt.Errorf("unknown metric of goal : %s, must be %q, %q, %q or %q", metric,
+		GoalMetricMerged, GoalMetricCommits, GoalMetricLGTMed, GoalMetricCreated)
+}
+
+// goalOf returns the goal of the metric of the user, goals of the user are preferred to those of teams,
+// which are preferred to default ones. it is nil if the user has no goal of the metric.
+func goalOf(user string, metric string) *Goal {
+	var teamGoal, defaultGoal *Goal
+	for i := range Config.Goals {
+		goal := &Config.Goals[i]
+		if goal.Metric != metric {
+			continue
+		}
+		switch {
+		case goal.User != "":
+			if goal.User == user {
+				return goal
+			}
+		case goal.Team != "":
+			for _, team := range Config.Teams {
+				if team.Name == goal.Team && team.hasMember(user) && teamGoal == nil {
+					teamGoal = goal
+				}
+			}
+		default:
+			defaultGoal = goal
+		}
+	}
+	if teamGoal != nil {
+		return teamGoal
+	}
+	return defaultGoal
+}
+
+// projectedValue returns the value at the end of the stat period at the pace of the time elapsed until now,
+// -1 if the stat period has no end.
+func projectedValue(achieved int, now time.Time) int {
+	if Config.StatEndTime.IsZero() {
+		return -1
+	}
+	if !now.Before(Config.StatEndTime) {
+		return achieved
+	}
+	elapsed := now.Sub(Config.StatBeginTime)
+	if elapsed <= 0 {
+		return achieved
+	}
+	period := Config.StatEndTime.Sub(Config.StatBeginTime)
+	return int(float64(achieved) * float64(period) / float64(elapsed))
+}
+
+// goalProgresses returns progress of goals of every configured user in order of goal metrics,
+// metrics are those of the stat period by user and repo.
+func goalProgresses(metrics []*PullRequestMetrics, now time.Time) []*GoalProgress {
+	byUser := make(map[string]*PullRequestMetrics)
+	for _, m := range merge(metrics) {
+		byUser[m.User] = m
+	}
+	var progresses []*GoalProgress
+	for _, user := range Config.Users {
+		m, found := byUser[user.Name]
+		if !found {
+			m = &PullRequestMetrics{User: user.Name}
+		}
+		for _, metric := range []string{GoalMetricMerged, GoalMetricCommits, GoalMetricLGTMed, GoalMetricCreated} {
+			goal := goalOf(user.Name, metric)
+			if goal == nil {
+				continue
+			}
+			achieved, _ := goalMetricValue(metric, m)
+			p := &GoalProgress{User: user.Name, Metric: metric, Achieved: achieved, Target: goal.Target,
+				Projected: projectedValue(achieved, now)}
+			switch {
+			case p.Achieved >= p.Target:
+				p.Status = GoalStatusMet
+			case p.Projected >= p.Target:
+				p.Status = GoalStatusOnTrack
+			default:
+				p.Status = GoalStatusBehind
+			}
+			progresses = append(progresses, p)
+		}
+	}
+	return progresses
+}
+
+// goalColumns returns headers and cells of goal progress columns of metrics with goals, "achieved / target",
+// percentage complete and the projected value of every metric by user, both are nil if there are no goals.
+func goalColumns(progresses []*GoalProgress) ([]string, map[string][]string) {
+	var metrics []string
+	for _, metric := range []string{GoalMetricMerged, GoalMetricCommits, GoalMetricLGTMed, GoalMetricCreated} {
+		for _, p := range progresses {
+			if p.Metric == metric {
+				metrics = append(metrics, metric)
+				break
+			}
+		}
+	}
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+	var header []string
+	for _, metric := range metrics {
+		title := goalMetricTitles[metric]
+		header = append(header, title+" Goal", title+" Complete", title+" Projected")
+	}
+	cells := make(map[string][]string)
+	for _, p := range progresses {
+		if cells[p.User] == nil {
+			cells[p.User] = goalCellsOf(header, nil, p.User)
+		}
+		for i, metric := range metrics {
+			if metric != p.Metric {
+				continue
+			}
+			cells[p.User][i*3] = fmt.Sprintf("%d / %d", p.Achieved, p.Target)
+			cells[p.User][i*3+1] = fmt.Sprintf("%.1f%%", p.Complete())
+			if p.Projected >= 0 {
+				cells[p.User][i*3+2] = strconv.Itoa(p.Projected)
+			}
+		}
+	}
+	return header, cells
+}
+
+// goalCellsOf returns goal progress cells of the user, "-" for metrics without goals of the user.
+func goalCellsOf(header []string, cells map[string][]string, user string) []string {
+	if c, found := cells[user]; found {
+		return c
+	}
+	c := make([]string, len(header))
+	for i := range c {
+		c[i] = "-"
+	}
+	return c
+}
+
+// UnmetGoals returns goals failing the gate, "achieved" fails goals whose achieved values are below targets,
+// "projected" fails goals whose values projected to the end of the stat period are below targets.
+func (m *OverallPullRequestMetrics) UnmetGoals(gate string) ([]*GoalProgress, error) {
+	if gate != GoalGateAchieved && gate != GoalGateProjected {
+		return nil, fmt.Errorf("unknown goal gate : %s, must be %q or %q", gate, GoalGateAchieved, GoalGateProjected)
+	}
+	// values can't be projected to the end of a stat period without end
+	if gate == GoalGateProjected && Config.StatEndTime.IsZero() {
+		return nil, fmt.Errorf("goal gate %q requires statEndTime to project values to", GoalGateProjected)
+	}
+	var unmet []*GoalProgress
+	for _, p := range goalProgresses(m.Overall, time.Now()) {
+		if p.failed(gate) {
+			unmet = append(unmet, p)
+		}
+	}
+	return unmet, nil
+}
@@ -0,0 +1,95 @@
+package githubstat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_goalProgresses(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	users, teams, goals := Config.Users, Config.Teams, Config.Goals
+	defer func() { Config.Users, Config.Teams, Config.Goals = users, teams, goals }()
+	Config.Users = []User{{Name: "bruceauyeung"}, {Name: "tanshanshan"}, {Name: "newcomer"}}
+	Config.Teams = []Team{{Name: "node", Members: []string{"tanshanshan", "newcomer"}}}
+	Config.Goals = []Goal{
+		{Metric: GoalMetricMerged, Target: 4},
+		{Team: "node", Metric: GoalMetricMerged, Target: 2},
+		{User: "newcomer", Metric: GoalMetricMerged, Target: 1},
+		{User: "bruceauyeung", Metric: GoalMetricCommits, Target: 10},
+	}
+	metrics := newTestServer().fetch(nil, Config.ThisWeekFirstDay).Overall
+
+	// two thirds of the stat period have elapsed
+	progresses := goalProgresses(metrics, *fakeTime("2016-12-01T00:00:00Z"))
+	want := []GoalProgress{
+		{User: "bruceauyeung", Metric: GoalMetricMerged, Achieved: 5, Target: 4, Projected: 7, Status: GoalStatusMet},
+		{User: "bruceauyeung", Metric: GoalMetricCommits, Achieved: 6, Target: 10, Projected: 8, Status: GoalStatusBehind},
+		{User: "tanshanshan", Metric: GoalMetricMerged, Achieved: 1, Target: 2, Projected: 1, Status: GoalStatusBehind},
+		{User: "newcomer", Metric: GoalMetricMerged, Achieved: 0, Target: 1, Projected: 0, Status: GoalStatusBehind},
+	}
+	if len(progresses) != len(want) {
+		t.Fatalf("got %d goals, want %d", len(progresses), len(want))
+	}
+	for i, p := range progresses {
+		if *p != want[i] {
+			t.Errorf("progress is %+v, want %+v", *p, want[i])
+		}
+	}
+	if complete := progresses[1].Complete(); complete != 60 {
+		t.Errorf("complete of commits of bruceauyeung is %v, want 60", complete)
+	}
+
+	// tanshanshan is on track at the pace of the first third of the stat period
+	if p := goalProgresses(metrics, *fakeTime("2016-10-31T00:00:00Z"))[2]; p.Projected != 3 || p.Status != GoalStatusOnTrack ||
+		p.failed(GoalGateProjected) || !p.failed(GoalGateAchieved) {
+		t.Errorf("progress of the first month is %+v", p)
+	}
+}
+
+func Test_goalColumns(t *testing.T) {
+	progresses := []*GoalProgress{
+		{User: "bruceauyeung", Metric: GoalMetricMerged, Achieved: 5, Target: 4, Projected: 7},
+		{User: "bruceauyeung", Metric: GoalMetricCommits, Achieved: 6, Target: 10, Projected: -1},
+		{User: "tanshanshan", Metric: GoalMetricMerged, Achieved: 1, Target: 2, Projected: 1},
+	}
+	header, cells := goalColumns(progresses)
+	if len(header) != 6 || header[0] != "Merged PRs Goal" || header[5] != "Merged Commits Projected" {
+		t.Errorf("header of goal columns is %v", header)
+	}
+	if got := strings.Join(cells["bruceauyeung"], ","); got != "5 / 4,125.0%,7,6 / 10,60.0%,-" {
+		t.Errorf("goal cells of bruceauyeung are %s", got)
+	}
+	if got := strings.Join(cells["tanshanshan"], ","); got != "1 / 2,50.0%,1,-,-,-" {
+		t.Errorf("goal cells of tanshanshan are %s", got)
+	}
+	if got := strings.Join(goalCellsOf(header, cells, "newcomer"), ","); got != "-,-,-,-,-,-" {
+		t.Errorf("goal cells of users without goals are %s", got)
+	}
+	if header, cells := goalColumns(nil); header != nil || cells != nil {
+		t.Errorf("goal columns without goals are %v, %v", header, cells)
+	}
+
+	// progress columns follow metrics of users in the table
+	var buf bytes.Buffer
+	writeMetricsTable(&buf, []*PullRequestMetrics{{User: "bruceauyeung", Merged: 5}}, progresses)
+	if table := buf.String(); !strings.Contains(table, "MERGED PRS GOAL") || !strings.Contains(table, "125.0%") {
+		t.Errorf("metrics table has no goal columns:\n%s", table)
+	}
+}
+
+func Test_UnmetGoalsWithoutStatEndTime(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "", "2016-12-24T00:00:00Z")()
+	goals := Config.Goals
+	defer func() { Config.Goals = goals }()
+	Config.Goals = []Goal{{Metric: GoalMetricMerged, Target: 4}}
+
+	// values can't be projected without the end of the stat period
+	m := &OverallPullRequestMetrics{}
+	if _, err := m.UnmetGoals(GoalGateProjected); err == nil || !strings.Contains(err.Error(), "statEndTime") {
+		t.Errorf("projected gate without statEndTime should fail, got %v", err)
+	}
+	if _, err := m.UnmetGoals(GoalGateAchieved); err != nil {
+		t.Errorf("achieved gate without statEndTime fails: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package githubstat
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -23,19 +24,78 @@ var Config Configuration
 type User struct {
 	Name     string
 	RealName string
+	// login names on hosts where they differ from Name, e.g. {"ghe.corp" = "bauyeung"}
+	Logins map[string]string
+	// commit author emails, by which commits in local clones are attributed to the user
+	Emails []string
+}
+
+// login returns login name of the user on the host.
+func (u *User) login(host string) string {
+	if login, found := u.Logins[host]; found {
+		return login
+	}
+	return u.Name
+}
+
+// Team is a group of users, metrics of teams are reported in their own sections.
+type Team struct {
+	Name    string
+	Members []string // names of users
+}
+
+func (t *Team) hasMember(userName string) bool {
+	for _, member := range t.Members {
+		if member == userName {
+			return true
+		}
+	}
+	return false
+}
+
+// Host is a GitHub Enterprise Server instance, or github.com with its own settings.
+type Host struct {
+	Name        string // host name used in repos, e.g. "ghe.corp" in "ghe.corp:team/repo"
+	Type        string // "github" (default), "gitlab" or "gitea"
+	BaseURL     string // e.g. "https://ghe.corp/api/v3/", "https://gitlab.com/api/v4/" or "https://gitea.corp/api/v1/"
+	UploadURL   string // e.g. "https://ghe.corp/api/uploads/"
+	GraphQLURL  string // defaults to "api/graphql" next to "api/v3/" of base url
+	AccessToken string
+	CABundle    string // path of PEM encoded CA certificates to trust besides system ones
+	Proxy       string // e.g. "http://proxy.corp:8080", defaults to proxy environment variables
 }
 
 type Configuration struct {
-	StatBeginTime    time.Time
-	StatEndTime      time.Time
-	AccessToken      string
-	Users            []User
-	Repos            []string
-	Metrics          string
-	Dimension        string
-	WeekFirstDay     time.Weekday
-	ThisWeekFirstDay time.Time
-	Sort             int
+	StatBeginTime      time.Time
+	StatEndTime        time.Time
+	AccessToken        string
+	Users              []User
+	Teams              []Team
+	Goals              []Goal
+	Repos              []string
+	Metrics            string
+	Dimension          string
+	WeekFirstDay       time.Weekday
+	ThisWeekFirstDay   time.Time
+	Sort               int
+	SizeThresholds     []int
+	PathBreakdown      bool
+	PathGroups         []PathGroup
+	LabelPrefixes      []string
+	ListClosedUnmerged bool
+	Details            bool
+	Compare            string
+	API                string
+	Hosts              []Host
+	Source             string
+	CloneDir           string
+	Clones             map[string]string
+	FixtureMode        string
+	FixtureDir         string
+	StorePath          string
+	RepoFilter         RepoFilter
+	Server             ServerConfig
+	Report             ReportConfig
 }
 
 func getWeekFirstDay(t time.Time) time.Time {
@@ -61,4 +121,49 @@ func init() {
 		panic("stat end time must be after stat begin time")
 	}
 	Config.ThisWeekFirstDay = getWeekFirstDay(time.Now())
+	if Config.CloneDir == "" {
+		Config.CloneDir = "."
+	}
+	if Config.FixtureDir == "" {
+		Config.FixtureDir = DefaultFixtureDir
+	}
+	if Config.StorePath == "" {
+		Config.StorePath = DefaultStorePath
+	}
+	if Config.Server.Listen == "" {
+		Config.Server.Listen = DefaultListen
+	}
+	if Config.Server.RefreshMinutes <= 0 {
+		Config.Server.RefreshMinutes = DefaultRefreshMinutes
+	}
+	if Config.Report.Email.Port == 0 {
+		Config.Report.Email.Port = defaultEmailPort(Config.Report.Email.TLS)
+	}
+	for _, host := range Config.Hosts {
+		if host.Name == "" {
+			panic("host name must be specified")
+		}
+		if host.Name != DefaultHost && host.BaseURL == "" {
+			panic(fmt.Sprintf("base url of host %s must be specified", host.Name))
+		}
+	}
+	for _, goal := range Config.Goals {
+		if _, err := goalMetricValue(goal.Metric, &PullRequestMetrics{}); err != nil {
+			panic(err)
+		}
+		if goal.User != "" && goal.Team != "" {
+			panic(fmt.Sprintf("goal of %s is of either a user or a team, not both", goal.Metric))
+		}
+	}
+	if len(Config.SizeThresholds) == 0 {
+		Config.SizeThresholds = DefaultSizeThresholds
+	}
+	if len(Config.SizeThresholds) != len(SizeBucketNames)-1 {
+		panic(fmt.Sprintf("sizeThresholds must contain exactly %d values", len(SizeBucketNames)-1))
+	}
+	for i := 1; i < len(Config.SizeThresholds); i++ {
+		if Config.SizeThresholds[i] <= Config.SizeThresholds[i-1] {
+			panic("sizeThresholds must be in ascending order")
+		}
+	}
 }
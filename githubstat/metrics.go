@@ -1,6 +1,9 @@
 package githubstat
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type Metrics interface {
 	Show()
@@ -21,7 +24,41 @@ type MetricsParameters struct {
 type RepoParameters struct {
 	OwnerName *string
 	RepoName  *string
+	Host      string // host name of the repository, empty means github.com
+	Exclude   bool   // repositories matching this one are excluded
 }
+
+// ParseRepo parses repository name of format "ownername/reponame" or "host:ownername/reponame".
+// owner name may contain slashes for GitLab subgroups, e.g. "gitlab.com:group/subgroup/project".
+// repo name may be a glob pattern, e.g. "kubernetes/kube-*", and a leading "!" excludes matching repos.
+func ParseRepo(repoStr string) (*RepoParameters, error) {
+	var host string
+	exclude := strings.HasPrefix(repoStr, "!")
+	repoStr = strings.TrimPrefix(repoStr, "!")
+	if i := strings.Index(repoStr, ":"); i >= 0 {
+		host = repoStr[:i]
+		repoStr = repoStr[i+1:]
+	}
+	i := strings.LastIndex(repoStr, "/")
+	if i <= 0 || i == len(repoStr)-1 {
+		return nil, fmt.Errorf("invalid repository name : %s, must be of format 'ownername/reponame' or 'host:ownername/reponame'", repoStr)
+	}
+	if host == DefaultHost {
+		host = ""
+	}
+	ownerName := repoStr[:i]
+	repoName := repoStr[i+1:]
+	return &RepoParameters{OwnerName: &ownerName, RepoName: &repoName, Host: host, Exclude: exclude}, nil
+}
+
+// String returns "ownername/reponame", prefixed with "host:" unless the repository is on github.com.
+func (r *RepoParameters) String() string {
+	if r.Host == "" {
+		return *r.OwnerName + "/" + *r.RepoName
+	}
+	return r.Host + ":" + *r.OwnerName + "/" + *r.RepoName
+}
+
 type DefaultMetrics struct{}
 
 type DefaultMetricsRequest struct{}
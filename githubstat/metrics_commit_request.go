@@ -8,11 +8,21 @@ import (
 	"github.com/google/go-github/github"
 )
 
+const (
+	// how commits are resolved to PRs
+	CommitResolutionAuthorSearch    = "search by author"    // searched by SHA among PRs of the commit author
+	CommitResolutionCommitterSearch = "search by committer" // searched by SHA among PRs of the committer
+	CommitResolutionPullRequest     = "commits of PR"       // listed from commits of merged PRs
+	CommitResolutionGitHistory      = "git history"         // derived from merge and squash commits of the default branch
+)
+
 type PullRequestCommit struct {
 	RepositoryCommit *github.RepositoryCommit
 	Owner            string
 	Repo             string
 	MergedAt         *time.Time
+	PullRequest      int    // number of the PR which merged the commit, 0 if not resolved
+	Resolution       string // how the commit is resolved to the PR
 }
 
 // sameCommitter means the pr author was same with the commit author
@@ -34,27 +44,40 @@ func (m *PullRequestCommit) findMergedTime(client *github.Client, sameCommitter
 			return false
 		}
 		m.MergedAt = pr.MergedAt
+		m.PullRequest = *pr.Number
+		m.Resolution = CommitResolutionAuthorSearch
+		if !sameCommitter {
+			m.Resolution = CommitResolutionCommitterSearch
+		}
 		return true
 	}
 	return false
 }
 
-func listCommits(client *github.Client, owner string, repo string, author string) ([]*github.RepositoryCommit, error) {
+// listCommits lists commits of author newer than the commit lastSHA until the end of the period,
+// all commits are listed if lastSHA is empty.
+func listCommits(client *github.Client, owner string, repo string, author string, lastSHA string, period statPeriod) ([]*github.RepositoryCommit, error) {
 	opt := &github.CommitsListOptions{
 		Author:      author,
-		Until:       Config.StatEndTime,
+		Until:       period.end,
 		ListOptions: github.ListOptions{PerPage: 100},
 	}
 
 	page := 1
 	var allCommits []*github.RepositoryCommit
+loop:
 	for {
 		commits, resp, err := client.Repositories.ListCommits(owner, repo, opt)
 		if err != nil {
 			return nil, err
 		}
 
-		allCommits = append(allCommits, commits...)
+		for _, c := range commits {
+			if lastSHA != "" && *c.SHA == lastSHA {
+				break loop
+			}
+			allCommits = append(allCommits, c)
+		}
 		if resp.NextPage == 0 {
 			break
 		}
@@ -128,10 +151,10 @@ func findPullRequest(client *github.Client, owner, repo, author, commitSHA strin
 
 }
 
-func getStackalyticsCommits(client *github.Client, owner string, repo string, author string) []*PullRequestCommit {
+func getStackalyticsCommits(client *github.Client, owner string, repo string, author string, lastSHA string, period statPeriod) []*PullRequestCommit {
 	//fmt.Printf("%s/%s : listing commits of stackalytics.com style\n", owner, repo)
 	var prCommits []*PullRequestCommit
-	commits, err := listCommits(client, owner, repo, author)
+	commits, err := listCommits(client, owner, repo, author, lastSHA, period)
 	if err != nil {
 		if strings.Contains(err.Error(), "409") && strings.Contains(err.Error(), "Git Repository is empty") {
 			return prCommits
@@ -141,7 +164,7 @@ func getStackalyticsCommits(client *github.Client, owner string, repo string, au
 	commits = filterCommits(commits)
 
 	for _, commit := range commits {
-		warpCommit := &PullRequestCommit{commit, owner, repo, nil}
+		warpCommit := &PullRequestCommit{RepositoryCommit: commit, Owner: owner, Repo: repo}
 		if !warpCommit.findMergedTime(client, true) {
 			//fmt.Printf("could not find pull request which includes this commit:%s \n", *commit.Commit.Message)
 			//fmt.Printf("change author to the committer:%s \n", *commit.Committer.Login)
@@ -150,12 +173,8 @@ func getStackalyticsCommits(client *github.Client, owner string, repo string, au
 			}
 		}
 
-		if warpCommit.MergedAt != nil && !warpCommit.MergedAt.Before(Config.StatBeginTime) {
-			if !Config.StatEndTime.IsZero() && warpCommit.MergedAt.Before(Config.StatEndTime) {
-				prCommits = append(prCommits, warpCommit)
-			} else if Config.StatEndTime.IsZero() {
-				prCommits = append(prCommits, warpCommit)
-			}
+		if warpCommit.MergedAt != nil && period.contains(warpCommit.MergedAt) {
+			prCommits = append(prCommits, warpCommit)
 		}
 	}
 	return prCommits
@@ -1,29 +1,28 @@
 package githubstat
 
 import (
-	"fmt"
-	"github.com/google/go-github/github"
-	"golang.org/x/oauth2"
 	"testing"
 )
 
-// var proxyClient = &ProxyClient{}
-// var client = proxyClient.getClient()
 var owner = "kubernetes"
 var repo = "kubernetes.github.io"
-var author = "xialonglee"
+var author = "bruceauyeung"
 
 //e.g. https://api.github.com/search/issues?page=1&per_page=100&q=[SHA]+repo:kubernetes/kubernetes.github.io+type:pr+author:[author]
 func Test_getStackalyticsCommits(t *testing.T) {
-	ts := &tokenSource{
-		&oauth2.Token{AccessToken: "put your token to test"},
-	}
-	tc := oauth2.NewClient(oauth2.NoContext, ts)
-	client := github.NewClient(tc)
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
 
-	wrapRepositoryCommits := getStackalyticsCommits(client, owner, repo, author)
+	wrapRepositoryCommits := getStackalyticsCommits(f.client(), owner, repo, author, "", configuredPeriod())
+	// "ccc" is a merge commit and "ddd" has no PR
+	want := map[string]string{"aaa": "2016-12-25T00:00:00Z", "bbb": "2016-10-10T00:00:00Z"}
+	if len(wrapRepositoryCommits) != len(want) {
+		t.Fatalf("got %d commits, want %d", len(wrapRepositoryCommits), len(want))
+	}
 	for _, c := range wrapRepositoryCommits {
-		fmt.Printf("commit message is: %s \n", *c.RepositoryCommit.Commit.Message)
-		fmt.Printf("merge time is: %s \n", c.MergedAt.String())
+		if mergedAt, found := want[*c.RepositoryCommit.SHA]; !found || !c.MergedAt.Equal(*fakeTime(mergedAt)) {
+			t.Errorf("commit %s is merged at %v, want %s", *c.RepositoryCommit.SHA, c.MergedAt, mergedAt)
+		}
 	}
 }
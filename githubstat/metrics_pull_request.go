@@ -2,6 +2,7 @@ package githubstat
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"time"
@@ -15,6 +16,9 @@ import (
 
 var (
 	LGTMLabels = []string{"LGTM", "Docs LGTM", "Tech Review LGTM"}
+
+	metricsTableHeader = []string{"User Name", "Merged PRs", "Merged Commits", "LGTM'ed PRs", "NonLGTM'ed PRs",
+		"Created PRs", "Closed Unmerged PRs", "Acceptance Rate"}
 )
 
 type AllPullRequestMetrics struct {
@@ -43,52 +47,67 @@ func (w *WeekPullRequestMetrics) Show() {
 		return
 	}
 	w.mergeAndSort()
+	if len(w.Week) != 0 {
+		fmt.Printf("\nStatistics for this Week ( week first day : %v)\n", Config.ThisWeekFirstDay)
+		writeMetricsTable(os.Stdout, w.Week, nil)
+		showDetails("Pull Requests and Commits of this Week", w.Week)
+	}
+}
+
+// writeMetricsTable writes a table of metrics merged by user with a "Total" row,
+// followed by goal progress columns if there are progresses of goals.
+func writeMetricsTable(writer io.Writer, rows []*PullRequestMetrics, progresses []*GoalProgress) {
+	goalHeader, goalCells := goalColumns(progresses)
 	data := [][]string{}
 	var totalMerged int
 	var totalMergedCommits int
 	var totalLGTMed int
 	var totalNonLGTMed int
 	var totalCreated int
+	var totalClosedUnmerged int
 
-	for _, metrics := range w.Week {
-		var user string
-
-		if realName := getRealName(metrics.User); realName != "" {
-			user = fmt.Sprintf("%s(%s)", metrics.User, realName)
-		} else {
-			user = metrics.User
-		}
-		r := []string{user, strconv.Itoa(metrics.Merged),
+	for _, metrics := range rows {
+		r := []string{userDisplayName(metrics.User), strconv.Itoa(metrics.Merged),
 			strconv.Itoa(metrics.MergedCommits), strconv.Itoa(metrics.LGTMed),
-			strconv.Itoa(metrics.NonLGTMed), strconv.Itoa(metrics.Created)}
+			strconv.Itoa(metrics.NonLGTMed), strconv.Itoa(metrics.Created),
+			strconv.Itoa(metrics.ClosedUnmerged), acceptanceRate(metrics.Merged, metrics.ClosedUnmerged)}
+		r = append(r, goalCellsOf(goalHeader, goalCells, metrics.User)...)
 		data = append(data, r)
 		totalMerged += metrics.Merged
 		totalMergedCommits += metrics.MergedCommits
 		totalLGTMed += metrics.LGTMed
 		totalNonLGTMed += metrics.NonLGTMed
 		totalCreated += metrics.Created
+		totalClosedUnmerged += metrics.ClosedUnmerged
 
 	}
-	if len(data) != 0 {
-		table := tablewriter.NewWriter(os.Stdout)
-		fmt.Printf("\nStatistics for this Week ( week first day : %v)\n", Config.ThisWeekFirstDay)
-		table.SetHeader([]string{"User Name", "Merged PRs", "Merged Commits",
-			"LGTM'ed PRs", "NonLGTM'ed PRs", "Created PRs"})
-		table.AppendBulk(data)
-		table.Append([]string{
-			"Total",
-			strconv.Itoa(totalMerged),
-			strconv.Itoa(totalMergedCommits),
-			strconv.Itoa(totalLGTMed),
-			strconv.Itoa(totalNonLGTMed),
-			strconv.Itoa(totalCreated)},
-		)
-		table.Render() // Send output
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader(append(append([]string{}, metricsTableHeader...), goalHeader...))
+	table.AppendBulk(data)
+	totalRow := []string{
+		"Total",
+		strconv.Itoa(totalMerged),
+		strconv.Itoa(totalMergedCommits),
+		strconv.Itoa(totalLGTMed),
+		strconv.Itoa(totalNonLGTMed),
+		strconv.Itoa(totalCreated),
+		strconv.Itoa(totalClosedUnmerged),
+		acceptanceRate(totalMerged, totalClosedUnmerged)}
+	// goals are of users, so there is no progress of the total
+	for range goalHeader {
+		totalRow = append(totalRow, "")
 	}
+	table.Append(totalRow)
+	table.Render() // Send output
 }
 
 type OverallPullRequestMetrics struct {
 	Overall []*PullRequestMetrics
+
+	// metrics of the previous window by user and repo, nil unless Config.Compare is set
+	Previous          []*PullRequestMetrics
+	PreviousBeginTime time.Time
+	PreviousEndTime   time.Time
 }
 type PullRequestMetrics struct {
 	User          string
@@ -96,7 +115,18 @@ type PullRequestMetrics struct {
 	MergedCommits int // the sum of commits number in merged PRs, is consistent with stackalytics.com's
 	LGTMed        int // open PRs with LGTM label
 	NonLGTMed     int //open PRs without LGTM label
-	Created       int // created PRs including all open PRs and all merged closed PRs
+	Created       int // PRs created in stat period, including open, merged and closed unmerged ones
+
+	Repo   string          // "owner/repo", empty after metrics of multiple repos are merged by user
+	Size   PullRequestSize // size of merged PRs
+	Paths  PathBreakdown   // changed lines of merged PRs by path group and language
+	Labels LabelBreakdown  // merged and created PRs by label
+	Trend  WeeklyTrend     // merged PRs, merged commits and created PRs by week, only of overall metrics
+
+	Details MetricsDetails // PRs and commits behind the numbers above
+
+	ClosedUnmerged    int                  // PRs closed without being merged
+	ClosedUnmergedPRs []*ClosedPullRequest // details of PRs closed without being merged
 }
 
 func (m *OverallPullRequestMetrics) mergeAndSort() {
@@ -107,28 +137,9 @@ func (m *OverallPullRequestMetrics) mergeAndSort() {
 }
 
 func (m *OverallPullRequestMetrics) Show() {
+	byRepo := mergeByRepo(m.Overall)
 	m.mergeAndSort()
-	data := [][]string{}
-	var totalMerged int
-	var totalMergedCommits int
-	var totalLGTMed int
-	var totalNonLGTMed int
-	for _, metrics := range m.Overall {
-		var user string
-		if realName := getRealName(metrics.User); realName != "" {
-			user = fmt.Sprintf("%s(%s)", metrics.User, realName)
-		} else {
-			user = metrics.User
-		}
-		r := []string{user, strconv.Itoa(metrics.Merged), strconv.Itoa(metrics.MergedCommits), strconv.Itoa(metrics.LGTMed), strconv.Itoa(metrics.NonLGTMed)}
-		data = append(data, r)
-		totalMerged += metrics.Merged
-		totalMergedCommits += metrics.MergedCommits
-		totalLGTMed += metrics.LGTMed
-		totalNonLGTMed += metrics.NonLGTMed
-	}
-	if len(data) != 0 {
-		table := tablewriter.NewWriter(os.Stdout)
+	if len(m.Overall) != 0 {
 		var endTime time.Time
 		if Config.StatEndTime.IsZero() {
 			endTime = time.Now()
@@ -136,19 +147,27 @@ func (m *OverallPullRequestMetrics) Show() {
 			endTime = Config.StatEndTime
 		}
 		fmt.Printf("\nOverall Statistics ( %v ~ %v)\n", Config.StatBeginTime, endTime)
-		table.SetHeader([]string{"User Name", "Merged PRs", "Merged Commits", "LGTM'ed PRs", "NonLGTM'ed PRs"})
-		table.AppendBulk(data)
-		table.Append([]string{
-			"Total",
-			strconv.Itoa(totalMerged),
-			strconv.Itoa(totalMergedCommits),
-			strconv.Itoa(totalLGTMed),
-			strconv.Itoa(totalNonLGTMed),
-		})
-		table.Render() // Send output
+		writeMetricsTable(os.Stdout, m.Overall, goalProgresses(m.Overall, time.Now()))
+		m.showComparison()
+
+		showPullRequestSizes("Merged Pull Request Size by User", "User Name", m.Overall,
+			func(metrics *PullRequestMetrics) string { return userDisplayName(metrics.User) })
+		showPullRequestSizes("Merged Pull Request Size by Repository", "Repository", byRepo,
+			func(metrics *PullRequestMetrics) string { return metrics.Repo })
+		showPathBreakdown(m.Overall)
+		showLabelBreakdown(m.Overall)
+		showAcceptanceByRepo(byRepo)
+		showClosedUnmergedPullRequests(m.Overall)
+		showDetails("Pull Requests and Commits of Overall Statistics", m.Overall)
 	}
 
 }
+func userDisplayName(userName string) string {
+	if realName := getRealName(userName); realName != "" {
+		return fmt.Sprintf("%s(%s)", userName, realName)
+	}
+	return userName
+}
 func getRealName(userName string) string {
 	for _, u := range Config.Users {
 		if u.Name == userName {
@@ -173,20 +192,49 @@ func sortMetrics(toBeSort []*PullRequestMetrics) []*PullRequestMetrics {
 	}
 }
 func merge(toBeMerged []*PullRequestMetrics) []*PullRequestMetrics {
-	// user name to slice index of the first occurence of user's metrics
+	return mergeBy(toBeMerged, func(metrics *PullRequestMetrics) string { return metrics.User })
+}
+
+// mergeByRepo merges metrics of all users in the same repository, the User field of result is empty.
+func mergeByRepo(toBeMerged []*PullRequestMetrics) []*PullRequestMetrics {
+	merged := mergeBy(toBeMerged, func(metrics *PullRequestMetrics) string { return metrics.Repo })
+	for _, metrics := range merged {
+		metrics.User = ""
+	}
+	return merged
+}
+
+// mergeBy sums up metrics with the same key, metrics to be merged are left untouched.
+func mergeBy(toBeMerged []*PullRequestMetrics, key func(*PullRequestMetrics) string) []*PullRequestMetrics {
+	// key to slice index of the first occurence of key's metrics
 	mapping := make(map[string]int)
 	var merged []*PullRequestMetrics
 	for _, metrics := range toBeMerged {
-		if i, found := mapping[metrics.User]; found {
+		k := key(metrics)
+		if i, found := mapping[k]; found {
 			prm := merged[i]
+			if prm.Repo != metrics.Repo {
+				prm.Repo = ""
+			}
 			prm.Merged += metrics.Merged
 			prm.MergedCommits += metrics.MergedCommits
 			prm.LGTMed += metrics.LGTMed
 			prm.NonLGTMed += metrics.NonLGTMed
 			prm.Created += metrics.Created
+			prm.Size.add(metrics.Size)
+			prm.Paths.add(metrics.Paths)
+			prm.Labels.add(metrics.Labels)
+			prm.Trend.add(metrics.Trend)
+			prm.Details.add(metrics.Details)
+			prm.ClosedUnmerged += metrics.ClosedUnmerged
+			prm.ClosedUnmergedPRs = append(append([]*ClosedPullRequest{}, prm.ClosedUnmergedPRs...),
+				metrics.ClosedUnmergedPRs...)
 		} else {
-			mapping[metrics.User] = len(merged)
-			merged = append(merged, metrics)
+			mapping[k] = len(merged)
+			// the copy shares maps and slices with metrics to be merged, so add methods of its fields put sums
+			// into new maps and slices instead of modifying them
+			copied := *metrics
+			merged = append(merged, &copied)
 		}
 	}
 
@@ -195,6 +243,20 @@ func merge(toBeMerged []*PullRequestMetrics) []*PullRequestMetrics {
 
 type PullRequestMetricsRequest struct {
 	param *MetricsParameters
+	// providers by host name
+	providers map[string]Provider
+	// source of pull requests, Config.Source is used if it is empty
+	source string
+	// api of GitHub hosts, Config.API is used if it is empty
+	api string
+	// users whose metrics are fetched, Config.Users is used if it is nil
+	users []User
+	// whether metrics are not compared with the previous window, e.g. those of the previous window themselves
+	noCompare bool
+	// period of metrics, the configured stat period is used if it is zero
+	period statPeriod
+	// first day of this week, Config.ThisWeekFirstDay is used if it is zero
+	weekFirstDay time.Time
 }
 
 func (m *PullRequestMetricsRequest) express() {
@@ -250,13 +312,35 @@ func listRepositories(client *github.Client, owner string, opt *github.Repositor
 	}
 	return allRepos, nil
 }
-func listOpenPullRequests(client *github.Client, owner string, repo string) ([]*github.PullRequest, error) {
+func listOrgRepositories(client *github.Client, org string, opt *github.RepositoryListByOrgOptions) ([]*github.Repository, error) {
+	var allRepos []*github.Repository
+	for {
+		repos, resp, err := client.Repositories.ListByOrg(org, opt)
+		if err != nil {
+			return nil, err
+		}
+		allRepos = append(allRepos, repos...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.ListOptions.Page = resp.NextPage
+		fmt.Printf("page:%d fin\n", resp.NextPage-1)
+	}
+	return allRepos, nil
+}
+func listOpenPullRequests(client *github.Client, owner string, repo string, period statPeriod) ([]*github.PullRequest, error) {
 	opt := &github.PullRequestListOptions{
 		ListOptions: github.ListOptions{PerPage: 100},
 		State:       "open",
 		Sort:        "created",
 		Direction:   "desc",
 	}
+	return listPullRequests(client, owner, repo, opt, period.filterOpen)
+}
+
+// listPullRequests lists PRs page by page, filter tells whether a PR is kept and whether listing should stop.
+func listPullRequests(client *github.Client, owner string, repo string, opt *github.PullRequestListOptions,
+	filter func(*github.PullRequest) (bool, bool)) ([]*github.PullRequest, error) {
 	var allPRs []*github.PullRequest
 
 	page := 1
@@ -269,14 +353,12 @@ loop:
 
 		fmt.Printf("page:%d fin\n", page)
 		for _, pr := range prs {
-			t := pr.CreatedAt
-			if !Config.StatEndTime.IsZero() && !t.Before(Config.StatEndTime) {
-				continue
+			keep, stop := filter(pr)
+			if stop {
+				break loop
 			}
-			if !t.Before(Config.StatBeginTime) {
+			if keep {
 				allPRs = append(allPRs, pr)
-			} else {
-				break loop
 			}
 		}
 
@@ -288,58 +370,71 @@ loop:
 	}
 	return allPRs, nil
 }
-func listClosedPullRequests(client *github.Client, owner string, repo string) ([]*github.PullRequest, error) {
+
+// filterOpen tells whether an open PR is kept, and whether listing should stop
+// because open PRs are listed by create time descendingly.
+func (p statPeriod) filterOpen(pr *github.PullRequest) (keep bool, stop bool) {
+	t := pr.CreatedAt
+	if !p.end.IsZero() && !t.Before(p.end) {
+		return false, false
+	}
+	if !t.Before(p.begin) {
+		return true, false
+	}
+	return false, true
+}
+
+// filterClosed tells whether a closed PR is kept, and whether listing should stop
+// because closed PRs are listed by update time descendingly.
+func (p statPeriod) filterClosed(pr *github.PullRequest) (keep bool, stop bool) {
+	t := pr.UpdatedAt
+	/*
+		MergedAt and ClosedAt are always before UpdatedAt, so if a PR is updated before stat begin time,
+		this PR is absolutely merged(closed) before stat begin time.
+		WARNING: UpdatedAt is sorted descendingly, but MergedAt is not. so we can break outer loop according to MergedAt
+	*/
+	if t.Before(p.begin) {
+		return false, true
+	}
+	// merged PRs are analyzed by merge time, closed unmerged PRs are analyzed by close time,
+	// and all closed PRs are analyzed by create time for created PRs.
+	if pr.MergedAt != nil && p.contains(pr.MergedAt) {
+		return true, false
+	} else if pr.MergedAt == nil && pr.ClosedAt != nil && p.contains(pr.ClosedAt) {
+		return true, false
+	}
+	return p.contains(pr.CreatedAt), false
+}
+func listClosedPullRequests(client *github.Client, owner string, repo string, period statPeriod) ([]*github.PullRequest, error) {
 	opt := &github.PullRequestListOptions{
 		ListOptions: github.ListOptions{PerPage: 100},
 		State:       "closed",
 		Sort:        "updated",
 		Direction:   "desc",
 	}
+	return listPullRequests(client, owner, repo, opt, period.filterClosed)
+}
 
-	var allPRs []*github.PullRequest
-
-	page := 1
-
-loop:
-	for {
-		prs, resp, err := client.PullRequests.List(owner, repo, opt)
-		if err != nil {
-			return nil, err
-		}
-		fmt.Printf("page:%d fin\n", page)
-		for _, pr := range prs {
-
-			if pr.MergedAt == nil {
-				continue
-			}
-			t := pr.UpdatedAt
-			/*
-				MergedAt is always before UpdatedAt, so if a PR is updated before stat begin time,
-				this PR is absolutely merged before stat begin time.
-				WARNING: UpdatedAt is sorted descendingly, but MergedAt is not. so we can break outer loop according to MergedAt
-			*/
-			if !t.Before(Config.StatBeginTime) {
-
-				if !pr.MergedAt.Before(Config.StatBeginTime) {
-					if !Config.StatEndTime.IsZero() && pr.MergedAt.Before(Config.StatEndTime) {
-						allPRs = append(allPRs, pr)
-					} else if Config.StatEndTime.IsZero() {
-						allPRs = append(allPRs, pr)
-					}
-				}
-			} else {
-				break loop
-			}
-		}
+// listUpdatedPullRequests lists open and closed PRs updated since the given time.
+func listUpdatedPullRequests(client *github.Client, owner string, repo string, since time.Time) ([]*github.PullRequest, error) {
+	opt := &github.PullRequestListOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+		State:       "all",
+		Sort:        "updated",
+		Direction:   "desc",
+	}
+	return listPullRequests(client, owner, repo, opt, filterUpdatedPullRequest(since))
+}
 
-		if resp.NextPage == 0 {
-			break
+// filterUpdatedPullRequest returns a filter keeping PRs updated since the given time, which stops listing
+// at the first PR updated before it because PRs are listed by update time descendingly.
+func filterUpdatedPullRequest(since time.Time) func(*github.PullRequest) (bool, bool) {
+	return func(pr *github.PullRequest) (bool, bool) {
+		if pr.UpdatedAt.Before(since) {
+			return false, true
 		}
-		opt.ListOptions.Page = resp.NextPage
-		page += 1
+		return true, false
 	}
-
-	return allPRs, nil
 }
 func getIssue(client *github.Client, owner string, repo string, number int) *github.Issue {
 	issue, _, err := client.Issues.Get(owner, repo, number)
@@ -359,7 +454,7 @@ func getPullRequestLabelNames(client *github.Client, owner string, repo string,
 	return labelNames
 
 }
-func getPullRequestLatestLGTMEvent(client *github.Client, owner string, repo string, number int) (*github.IssueEvent, error) {
+func getPullRequestLatestLGTMEvent(client *github.Client, owner string, repo string, number int, period statPeriod) (*github.IssueEvent, error) {
 	//var allEvents []*github.IssueEvent
 	page := 1
 	opt := &github.ListOptions{PerPage: 100}
@@ -376,7 +471,7 @@ func getPullRequestLatestLGTMEvent(client *github.Client, owner string, repo str
 		}
 		//allEvents = append(allEvents, events...)
 		fmt.Printf("page:%d fin\n", page)
-		if resp.NextPage == 0 || events[len(events)-1].CreatedAt.Before(Config.StatBeginTime) {
+		if resp.NextPage == 0 || events[len(events)-1].CreatedAt.Before(period.begin) {
 			break
 		}
 		opt.Page = resp.NextPage
@@ -386,8 +481,10 @@ func getPullRequestLatestLGTMEvent(client *github.Client, owner string, repo str
 
 }
 func isLGTMed(client *github.Client, owner string, repo string, number int) bool {
-	lnames := getPullRequestLabelNames(client, owner, repo, number)
-	if StringSliceContainsAnyFold(lnames, LGTMLabels...) {
+	return hasLGTMLabel(getPullRequestLabelNames(client, owner, repo, number))
+}
+func hasLGTMLabel(labelNames []string) bool {
+	if StringSliceContainsAnyFold(labelNames, LGTMLabels...) {
 		return true
 	}
 	return false
@@ -430,28 +527,32 @@ func filterByUserName(prs []*github.PullRequest, userName string) []*github.Pull
 	}
 	return filtered
 }
-func (m *PullRequestMetricsRequest) expandRepos(client *github.Client) {
-	var expanded []*RepoParameters
-	for _, repo := range m.param.Repos {
-		ownerName := *repo.OwnerName
-		repoName := *repo.RepoName
-		if repoName == "*" {
-			repos, err := listRepositories(client, ownerName, &github.RepositoryListOptions{
-				ListOptions: github.ListOptions{PerPage: 100}})
 
-			if err != nil {
-				panic(err)
-			}
-			for _, r := range repos {
-				expanded = append(expanded, &RepoParameters{r.Owner.Login, r.Name})
-			}
-
-		} else {
-			expanded = append(expanded, repo)
+// providerOf returns provider of the host, providers are created only once per host.
+func (m *PullRequestMetricsRequest) providerOf(host string) Provider {
+	if m.providers == nil {
+		m.providers = make(map[string]Provider)
+	}
+	provider, found := m.providers[host]
+	if !found {
+		source := m.source
+		if source == "" {
+			source = Config.Source
+		}
+		switch source {
+		case "", SourceAPI:
+			provider = newProvider(getProxyClient(host), m.api, m.period)
+		case SourceGit:
+			// local clones are analyzed without any API call
+			provider = newGitProvider(host, m.period)
+		case SourceStore:
+			provider = newStoreProvider(host, m.period)
+		default:
+			panic(fmt.Sprintf("unknown source : %s, must be %q, %q or %q", source, SourceAPI, SourceGit, SourceStore))
 		}
+		m.providers[host] = provider
 	}
-
-	m.param.Repos = expanded
+	return provider
 }
 func sumCommits(prs []*github.PullRequest) int {
 	var sum int
@@ -462,9 +563,49 @@ func sumCommits(prs []*github.PullRequest) int {
 	}
 	return sum
 }
-func inThisWeek(t *time.Time) bool {
 
-	if !t.Before(Config.ThisWeekFirstDay) && !t.After(time.Now()) {
+// statPeriod is the period which metrics are computed in, it has no end if end is zero.
+type statPeriod struct {
+	begin time.Time
+	end   time.Time
+}
+
+// configuredPeriod returns the period from stat begin time to stat end time.
+func configuredPeriod() statPeriod {
+	return statPeriod{begin: Config.StatBeginTime, end: Config.StatEndTime}
+}
+
+// orConfigured returns the configured period if p is zero, so that requests and providers without a period
+// use the configured one.
+func (p statPeriod) orConfigured() statPeriod {
+	if p.begin.IsZero() && p.end.IsZero() {
+		return configuredPeriod()
+	}
+	return p
+}
+
+// endOrNow returns the end of the period, now if it has no end.
+func (p statPeriod) endOrNow() time.Time {
+	if p.end.IsZero() {
+		return time.Now()
+	}
+	return p.end
+}
+
+// contains reports whether t is between begin (included) and end (excluded).
+func (p statPeriod) contains(t *time.Time) bool {
+	if t.Before(p.begin) {
+		return false
+	}
+	return p.end.IsZero() || t.Before(p.end)
+}
+
+func (m *PullRequestMetricsRequest) inThisWeek(t *time.Time) bool {
+	weekFirstDay := m.weekFirstDay
+	if weekFirstDay.IsZero() {
+		weekFirstDay = Config.ThisWeekFirstDay
+	}
+	if !t.Before(weekFirstDay) && !t.After(time.Now()) {
 		return true
 	}
 
@@ -473,75 +614,118 @@ func inThisWeek(t *time.Time) bool {
 func (m *PullRequestMetricsRequest) FetchMetrics() Metrics {
 
 	m.express()
+	period := m.period.orConfigured()
+	if Config.Compare != "" && !m.noCompare {
+		// unknown compare and periods overlapping the previous window panic before fetching metrics,
+		// which may take hours
+		previousWindow(Config.Compare, period.begin, period.endOrNow())
+	}
 
-	proxyClient := &ProxyClient{}
-	client := proxyClient.getClient()
 	if m.validate() {
 
 		var metrics OverallPullRequestMetrics = OverallPullRequestMetrics{Overall: []*PullRequestMetrics{}}
 		var weekMetrics WeekPullRequestMetrics = WeekPullRequestMetrics{Week: []*PullRequestMetrics{}}
 		var all AllPullRequestMetrics = AllPullRequestMetrics{WeekPullRequestMetrics: &weekMetrics, OverallPullRequestMetrics: &metrics}
-		m.expandRepos(client)
+		m.expandRepos()
 
 		for _, repo := range m.param.Repos {
 			ownerName := *repo.OwnerName
 			repoName := *repo.RepoName
-			fmt.Printf("%s/%s : listing open pull requests\n", ownerName, repoName)
+			provider := m.providerOf(repo.Host)
+			fmt.Printf("%s : listing open pull requests\n", repo)
 
-			openPRs, err := listOpenPullRequests(client, ownerName, repoName)
+			openPRs, err := provider.ListOpenPullRequests(ownerName, repoName)
 			if err != nil {
 				panic(err)
 			}
 
-			fmt.Printf("%s/%s : listing closed pull requests\n", ownerName, repoName)
-			closedPRs, err := listClosedPullRequests(client, ownerName, repoName)
+			fmt.Printf("%s : listing closed pull requests\n", repo)
+			closedPRs, err := provider.ListClosedPullRequests(ownerName, repoName)
 			if err != nil {
 				panic(err)
 			}
 
-			for _, user := range Config.Users {
+			users := m.users
+			if users == nil {
+				users = Config.Users
+			}
+			for _, user := range users {
 				var overallMergedPRs []*github.PullRequest
 				var overallLGTMedPRs []*github.PullRequest
 				var overallNonLGTMedPRs []*github.PullRequest
-				var overallStackalyticsCommits []*PullRequestCommit
 				var weekMergedPRs []*github.PullRequest
 				var weekLGTMedPRs []*github.PullRequest
 				var weekNonLGTMedPRs []*github.PullRequest
+				var overallCreatedPRs []*github.PullRequest
 				var weekCreatedPRs []*github.PullRequest
 				var weekStackalyticsCommits []*PullRequestCommit
+				var overallPaths PathBreakdown
+				var weekPaths PathBreakdown
+				var overallLabels LabelBreakdown
+				var overallTrend WeeklyTrend
+				var weekLabels LabelBreakdown
+				var overallClosedUnmergedPRs []*ClosedPullRequest
+				var weekClosedUnmergedPRs []*ClosedPullRequest
+				var overallDetails MetricsDetails
+				var weekDetails MetricsDetails
 				userName := user.Name
+				login := user.login(repo.Host)
 
 				//fmt.Printf("%s/%s : listing stackalytics style commits\n", ownerName, repoName)
-				overallStackalyticsCommits = getStackalyticsCommits(client, ownerName, repoName, userName)
-				filteredOpenPRs := filterByUserName(openPRs, userName)
-				filteredClosedPRs := filterByUserName(closedPRs, userName)
+				overallStackalyticsCommits, err := provider.ListMergedCommits(ownerName, repoName, login)
+				if err != nil {
+					panic(err)
+				}
+				filteredOpenPRs := filterByUserName(openPRs, login)
+				filteredClosedPRs := filterByUserName(closedPRs, login)
 
 				for _, c := range overallStackalyticsCommits {
-					if inThisWeek(c.MergedAt) {
+					overallTrend.addMergedCommit(c.MergedAt)
+					detail := newCommitDetail(repo, userName, c)
+					overallDetails.MergedCommits = append(overallDetails.MergedCommits, detail)
+					if m.inThisWeek(c.MergedAt) {
 						weekStackalyticsCommits = append(weekStackalyticsCommits, c)
+						weekDetails.MergedCommits = append(weekDetails.MergedCommits, detail)
 					}
 				}
 
 				for _, pr := range filteredOpenPRs {
-					if inThisWeek(pr.CreatedAt) {
+					// open PRs are listed by create time, so all of them are created in stat period.
+					overallCreatedPRs = append(overallCreatedPRs, pr)
+					overallTrend.addCreated(pr.CreatedAt)
+					labelNames, err := provider.GetLabelNames(ownerName, repoName, *pr.Number)
+					if err != nil {
+						panic(err)
+					}
+					overallLabels.addCreated(labelNames)
+					detail := newPullRequestDetail(repo, userName, pr, labelNames)
+					overallDetails.Created = append(overallDetails.Created, detail)
+					if m.inThisWeek(pr.CreatedAt) {
 						weekCreatedPRs = append(weekCreatedPRs, pr)
+						weekLabels.addCreated(labelNames)
+						weekDetails.Created = append(weekDetails.Created, detail)
 					}
-					if isLGTMed(client, ownerName, repoName, *pr.Number) {
+					if hasLGTMLabel(labelNames) {
 						overallLGTMedPRs = append(overallLGTMedPRs, pr)
+						overallDetails.LGTMed = append(overallDetails.LGTMed, detail)
 
-						if event, err := getPullRequestLatestLGTMEvent(client, ownerName, repoName, *pr.Number); err != nil {
+						if event, err := provider.GetLatestLGTMEvent(ownerName, repoName, *pr.Number); err != nil {
 							panic(err)
 						} else {
+							detail.LGTMAt = event.CreatedAt
 
-							if inThisWeek(event.CreatedAt) {
+							if m.inThisWeek(event.CreatedAt) {
 								weekLGTMedPRs = append(weekLGTMedPRs, pr)
+								weekDetails.LGTMed = append(weekDetails.LGTMed, detail)
 							}
 						}
 
 					} else {
 						overallNonLGTMedPRs = append(overallNonLGTMedPRs, pr)
-						if inThisWeek(pr.CreatedAt) {
+						overallDetails.NonLGTMed = append(overallDetails.NonLGTMed, detail)
+						if m.inThisWeek(pr.CreatedAt) {
 							weekNonLGTMedPRs = append(weekNonLGTMedPRs, pr)
+							weekDetails.NonLGTMed = append(weekDetails.NonLGTMed, detail)
 						}
 					}
 				}
@@ -551,19 +735,55 @@ func (m *PullRequestMetricsRequest) FetchMetrics() Metrics {
 					//fmt.Printf("pr title : %#v\n", *pr.Title)
 					//fmt.Printf("pr is merged ? %#v\n", pr.MergedAt != nil)
 
+					// labels of closed PRs are only needed by label breakdown
+					var labelNames []string
+					if len(Config.LabelPrefixes) != 0 {
+						if labelNames, err = provider.GetLabelNames(ownerName, repoName, *pr.Number); err != nil {
+							panic(err)
+						}
+					}
+					// closed PRs created in stat period may be merged(closed) after stat end time.
+					if period.contains(pr.CreatedAt) {
+						overallCreatedPRs = append(overallCreatedPRs, pr)
+						overallLabels.addCreated(labelNames)
+						overallTrend.addCreated(pr.CreatedAt)
+						detail := newPullRequestDetail(repo, userName, pr, labelNames)
+						overallDetails.Created = append(overallDetails.Created, detail)
+						if m.inThisWeek(pr.CreatedAt) {
+							weekCreatedPRs = append(weekCreatedPRs, pr)
+							weekLabels.addCreated(labelNames)
+							weekDetails.Created = append(weekDetails.Created, detail)
+						}
+					}
+
 					if pr.MergedAt != nil {
+						if !period.contains(pr.MergedAt) {
+							continue
+						}
 						//get the specified pull request to fill in all other blank fields (such as Commits field)
-						pr, err := getPullRequest(client, ownerName, repoName, *pr.Number)
+						pr, err := provider.GetPullRequest(ownerName, repoName, *pr.Number)
 						if err != nil {
 							panic(err)
 						}
 						overallMergedPRs = append(overallMergedPRs, pr)
-						if inThisWeek(pr.MergedAt) {
+						prPaths := getPathBreakdown(provider, ownerName, repoName, *pr.Number)
+						overallPaths.add(prPaths)
+						overallLabels.addMerged(labelNames)
+						overallTrend.addMerged(pr.MergedAt)
+						detail := newPullRequestDetail(repo, userName, pr, labelNames)
+						overallDetails.Merged = append(overallDetails.Merged, detail)
+						if m.inThisWeek(pr.MergedAt) {
 							weekMergedPRs = append(weekMergedPRs, pr)
+							weekPaths.add(prPaths)
+							weekLabels.addMerged(labelNames)
+							weekDetails.Merged = append(weekDetails.Merged, detail)
 							//fmt.Printf("pr title: %s, \npr merged at :%v\n", *pr.Title, *pr.MergedAt)
 						}
-						if inThisWeek(pr.CreatedAt) {
-							weekCreatedPRs = append(weekCreatedPRs, pr)
+					} else if pr.ClosedAt != nil && period.contains(pr.ClosedAt) {
+						closed := newClosedPullRequest(provider, repo, userName, pr)
+						overallClosedUnmergedPRs = append(overallClosedUnmergedPRs, closed)
+						if m.inThisWeek(pr.ClosedAt) {
+							weekClosedUnmergedPRs = append(weekClosedUnmergedPRs, closed)
 						}
 					}
 				}
@@ -578,30 +798,51 @@ func (m *PullRequestMetricsRequest) FetchMetrics() Metrics {
 
 				metrics.Overall = append(metrics.Overall, &PullRequestMetrics{
 					User:          userName,
+					Repo:          repo.String(),
 					Merged:        lenMergedPRs,
 					MergedCommits: lenStackCommits,
 					LGTMed:        lenLGTMedPRs,
 					NonLGTMed:     lenNonLGTMed,
-					Created:       -1,
+					Created:       len(overallCreatedPRs),
+					Size:          sumPullRequestSizes(overallMergedPRs),
+					Paths:         overallPaths,
+					Labels:        overallLabels,
+					Trend:         overallTrend,
+					Details:       overallDetails,
+
+					ClosedUnmerged:    len(overallClosedUnmergedPRs),
+					ClosedUnmergedPRs: overallClosedUnmergedPRs,
 				})
 
 				weekMetrics.Week = append(weekMetrics.Week, &PullRequestMetrics{
 					User:          userName,
+					Repo:          repo.String(),
 					Merged:        len(weekMergedPRs),
 					MergedCommits: len(weekStackalyticsCommits),
 					LGTMed:        len(weekLGTMedPRs),
 					NonLGTMed:     len(weekNonLGTMedPRs),
 					Created:       len(weekCreatedPRs),
+					Size:          sumPullRequestSizes(weekMergedPRs),
+					Paths:         weekPaths,
+					Labels:        weekLabels,
+					Details:       weekDetails,
+
+					ClosedUnmerged:    len(weekClosedUnmergedPRs),
+					ClosedUnmergedPRs: weekClosedUnmergedPRs,
 				})
 
 			}
 		}
 
+		if Config.Compare != "" && !m.noCompare {
+			previous, begin, end := m.fetchPrevious(period)
+			metrics.Previous, metrics.PreviousBeginTime, metrics.PreviousEndTime = previous.Overall, begin, end
+		}
 		return &all
 	}
 	return &AllPullRequestMetrics{
 		OverallPullRequestMetrics: &OverallPullRequestMetrics{
-			[]*PullRequestMetrics{
+			Overall: []*PullRequestMetrics{
 				&PullRequestMetrics{
 					User:          "",
 					Merged:        -1,
@@ -0,0 +1,98 @@
+package githubstat
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/olekukonko/tablewriter"
+)
+
+// ClosedPullRequest is a PR closed without being merged, i.e. rejected or abandoned.
+type ClosedPullRequest struct {
+	User     string
+	Repo     string
+	Number   int
+	Title    string
+	URL      string
+	ClosedAt time.Time
+	ClosedBy string // login of the user who closed the PR, empty if not fetched
+}
+
+// newClosedPullRequest fills in who closed the PR only when closed unmerged PRs are going to be listed,
+// because it costs one more API call per PR.
+func newClosedPullRequest(provider Provider, repo *RepoParameters, user string, pr *github.PullRequest) *ClosedPullRequest {
+	closed := &ClosedPullRequest{
+		User:   user,
+		Repo:   repo.String(),
+		Number: *pr.Number,
+	}
+	if pr.Title != nil {
+		closed.Title = *pr.Title
+	}
+	if pr.HTMLURL != nil {
+		closed.URL = *pr.HTMLURL
+	}
+	if pr.ClosedAt != nil {
+		closed.ClosedAt = *pr.ClosedAt
+	}
+	if Config.ListClosedUnmerged {
+		closedBy, err := provider.GetClosedBy(*repo.OwnerName, *repo.RepoName, *pr.Number)
+		if err != nil {
+			panic(err)
+		}
+		closed.ClosedBy = closedBy
+	}
+	return closed
+}
+
+// acceptanceRate is merged / (merged + closed unmerged).
+func acceptanceRate(merged int, closedUnmerged int) string {
+	if merged+closedUnmerged <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(merged)*100/float64(merged+closedUnmerged))
+}
+
+func showAcceptanceByRepo(byRepo []*PullRequestMetrics) {
+	data := [][]string{}
+	var totalMerged int
+	var totalClosedUnmerged int
+	for _, metrics := range byRepo {
+		data = append(data, []string{metrics.Repo, strconv.Itoa(metrics.Merged),
+			strconv.Itoa(metrics.ClosedUnmerged), acceptanceRate(metrics.Merged, metrics.ClosedUnmerged)})
+		totalMerged += metrics.Merged
+		totalClosedUnmerged += metrics.ClosedUnmerged
+	}
+	if len(data) != 0 {
+		table := tablewriter.NewWriter(os.Stdout)
+		fmt.Printf("\nPull Request Acceptance by Repository\n")
+		table.SetHeader([]string{"Repository", "Merged PRs", "Closed Unmerged PRs", "Acceptance Rate"})
+		table.AppendBulk(data)
+		table.Append([]string{"Total", strconv.Itoa(totalMerged), strconv.Itoa(totalClosedUnmerged),
+			acceptanceRate(totalMerged, totalClosedUnmerged)})
+		table.Render()
+	}
+}
+
+func showClosedUnmergedPullRequests(metrics []*PullRequestMetrics) {
+	if !Config.ListClosedUnmerged {
+		return
+	}
+	data := [][]string{}
+	for _, m := range metrics {
+		for _, pr := range m.ClosedUnmergedPRs {
+			data = append(data, []string{userDisplayName(pr.User), pr.Repo, "#" + strconv.Itoa(pr.Number),
+				pr.Title, pr.ClosedAt.Format("2006-01-02 15:04"), pr.ClosedBy, pr.URL})
+		}
+	}
+	if len(data) != 0 {
+		table := tablewriter.NewWriter(os.Stdout)
+		fmt.Printf("\nClosed Unmerged Pull Requests\n")
+		table.SetHeader([]string{"User Name", "Repository", "PR", "Title", "Closed At", "Closed By", "URL"})
+		table.AppendBulk(data)
+		table.Render()
+	}
+}
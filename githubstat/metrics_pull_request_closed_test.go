@@ -0,0 +1,60 @@
+package githubstat
+
+import "testing"
+
+func Test_acceptanceRate(t *testing.T) {
+	cases := []struct {
+		merged, closedUnmerged int
+		want                   string
+	}{
+		{0, 0, "-"},
+		{0, 2, "0.0%"},
+		{3, 0, "100.0%"},
+		{2, 1, "66.7%"},
+	}
+	for _, c := range cases {
+		if rate := acceptanceRate(c.merged, c.closedUnmerged); rate != c.want {
+			t.Errorf("acceptanceRate(%d, %d) = %q, want %q", c.merged, c.closedUnmerged, rate, c.want)
+		}
+	}
+}
+
+func Test_closedUnmerged(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	users, listClosedUnmerged := Config.Users, Config.ListClosedUnmerged
+	defer func() { Config.Users, Config.ListClosedUnmerged = users, listClosedUnmerged }()
+	Config.Users = []User{{Name: "bruceauyeung"}, {Name: "tanshanshan"}}
+	Config.ListClosedUnmerged = true
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+	// PR 3 of tanshanshan was closed in November by a reviewer
+	f.closedBy[3] = "bruceauyeung"
+
+	repo, err := ParseRepo("kubernetes/kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &PullRequestMetricsRequest{providers: map[string]Provider{"": &restProvider{client: f.client()}}}
+	m.SetParameters(&MetricsParameters{Repos: []*RepoParameters{repo}})
+	all := m.FetchMetrics().(*AllPullRequestMetrics)
+
+	overall := merge(all.Overall)
+	if bruce := overall[0]; bruce.ClosedUnmerged != 0 || len(bruce.ClosedUnmergedPRs) != 0 {
+		t.Errorf("merged PRs of bruceauyeung should not be closed unmerged, got %+v", bruce.ClosedUnmergedPRs)
+	}
+	tan := overall[1]
+	if tan.ClosedUnmerged != 1 || len(tan.ClosedUnmergedPRs) != 1 {
+		t.Fatalf("tanshanshan has %d closed unmerged PRs, want 1", tan.ClosedUnmerged)
+	}
+	if pr := tan.ClosedUnmergedPRs[0]; pr.Number != 3 || pr.Repo != "kubernetes/kubernetes" || pr.Title != "PR 3" ||
+		pr.ClosedBy != "bruceauyeung" || !pr.ClosedAt.Equal(*fakeTime("2016-11-05T00:00:00Z")) {
+		t.Errorf("closed unmerged PR is %+v", pr)
+	}
+	if week := merge(all.Week)[1]; week.ClosedUnmerged != 0 {
+		t.Errorf("PR 3 was not closed this week, got %d", week.ClosedUnmerged)
+	}
+	byRepo := mergeByRepo(all.Overall)
+	if rate := acceptanceRate(byRepo[0].Merged, byRepo[0].ClosedUnmerged); rate != "66.7%" {
+		t.Errorf("acceptance rate of kubernetes/kubernetes is %s, want 66.7%%", rate)
+	}
+}
@@ -0,0 +1,175 @@
+package githubstat
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/olekukonko/tablewriter"
+)
+
+// PullRequestDetail is a PR counted in a column of metrics.
+type PullRequestDetail struct {
+	User      string
+	Repo      string
+	Number    int
+	Title     string
+	URL       string
+	CreatedAt time.Time
+	MergedAt  *time.Time // nil if the PR is not merged
+	LGTMLabel string     // LGTM label of the PR, empty if it has none or labels of closed PRs are not fetched
+	LGTMAt    *time.Time // time of the latest LGTM event, only fetched for open PRs with LGTM label
+}
+
+// CommitDetail is a merged commit counted in metrics, with the PR it is resolved to.
+type CommitDetail struct {
+	User           string
+	Repo           string
+	SHA            string
+	Title          string // the first line of commit message
+	URL            string
+	PullRequest    int
+	PullRequestURL string
+	MergedAt       time.Time
+	Resolution     string // how the commit is resolved to the PR, e.g. "search by author"
+}
+
+// MetricsDetails are PRs and commits behind the numbers of metrics, PRs closed without being merged
+// are detailed by ClosedUnmergedPRs of metrics.
+type MetricsDetails struct {
+	Merged        []*PullRequestDetail
+	MergedCommits []*CommitDetail
+	LGTMed        []*PullRequestDetail
+	NonLGTMed     []*PullRequestDetail
+	Created       []*PullRequestDetail
+}
+
+// add appends details of another metrics.
+func (d *MetricsDetails) add(other MetricsDetails) {
+	d.Merged = append(append([]*PullRequestDetail{}, d.Merged...), other.Merged...)
+	d.MergedCommits = append(append([]*CommitDetail{}, d.MergedCommits...), other.MergedCommits...)
+	d.LGTMed = append(append([]*PullRequestDetail{}, d.LGTMed...), other.LGTMed...)
+	d.NonLGTMed = append(append([]*PullRequestDetail{}, d.NonLGTMed...), other.NonLGTMed...)
+	d.Created = append(append([]*PullRequestDetail{}, d.Created...), other.Created...)
+}
+
+// webPath returns url of the path in the repo on its web site, empty if the host is not GitHub.
+func webPath(repo *RepoParameters, path string) string {
+	base := webURLOf(repo.Host)
+	if base == "" {
+		return ""
+	}
+	return base + "/" + *repo.OwnerName + "/" + *repo.RepoName + "/" + path
+}
+
+// newPullRequestDetail returns detail of the PR, labelNames are used to find its LGTM label.
+func newPullRequestDetail(repo *RepoParameters, user string, pr *github.PullRequest, labelNames []string) *PullRequestDetail {
+	detail := &PullRequestDetail{
+		User:     user,
+		Repo:     repo.String(),
+		Number:   *pr.Number,
+		MergedAt: pr.MergedAt,
+	}
+	if pr.Title != nil {
+		detail.Title = *pr.Title
+	}
+	if pr.HTMLURL != nil {
+		detail.URL = *pr.HTMLURL
+	} else {
+		detail.URL = webPath(repo, "pull/"+strconv.Itoa(*pr.Number))
+	}
+	if pr.CreatedAt != nil {
+		detail.CreatedAt = *pr.CreatedAt
+	}
+	for _, name := range labelNames {
+		if isLGTMLabel(name) {
+			detail.LGTMLabel = name
+			break
+		}
+	}
+	return detail
+}
+
+func newCommitDetail(repo *RepoParameters, user string, c *PullRequestCommit) *CommitDetail {
+	sha := *c.RepositoryCommit.SHA
+	detail := &CommitDetail{
+		User:        user,
+		Repo:        repo.String(),
+		SHA:         sha,
+		PullRequest: c.PullRequest,
+		Resolution:  c.Resolution,
+	}
+	if c.RepositoryCommit.Commit != nil && c.RepositoryCommit.Commit.Message != nil {
+		detail.Title = strings.SplitN(*c.RepositoryCommit.Commit.Message, "\n", 2)[0]
+	}
+	if c.RepositoryCommit.HTMLURL != nil {
+		detail.URL = *c.RepositoryCommit.HTMLURL
+	} else {
+		detail.URL = webPath(repo, "commit/"+sha)
+	}
+	if c.PullRequest != 0 {
+		detail.PullRequestURL = webPath(repo, "pull/"+strconv.Itoa(c.PullRequest))
+	}
+	if c.MergedAt != nil {
+		detail.MergedAt = *c.MergedAt
+	}
+	return detail
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// showDetails lists PRs and commits behind every column of metrics merged by user.
+func showDetails(title string, metrics []*PullRequestMetrics) {
+	if !Config.Details {
+		return
+	}
+	data := [][]string{}
+	prRows := func(user string, column string, prs []*PullRequestDetail) {
+		for _, pr := range prs {
+			lgtm := pr.LGTMLabel
+			if pr.LGTMAt != nil {
+				lgtm += " at " + formatTime(pr.LGTMAt)
+			}
+			data = append(data, []string{userDisplayName(user), column, pr.Repo, "#" + strconv.Itoa(pr.Number), pr.Title,
+				formatTime(&pr.CreatedAt), formatTime(pr.MergedAt), lgtm, "", pr.URL})
+		}
+	}
+	for _, m := range metrics {
+		prRows(m.User, "Merged PRs", m.Details.Merged)
+		for _, c := range m.Details.MergedCommits {
+			sha := c.SHA
+			if len(sha) > 7 {
+				sha = sha[:7]
+			}
+			pr := ""
+			if c.PullRequest != 0 {
+				pr = "#" + strconv.Itoa(c.PullRequest)
+			}
+			data = append(data, []string{userDisplayName(m.User), "Merged Commits", c.Repo, sha,
+				c.Title, "", formatTime(&c.MergedAt), "", strings.TrimSpace(pr + " " + c.Resolution), c.URL})
+		}
+		prRows(m.User, "LGTM'ed PRs", m.Details.LGTMed)
+		prRows(m.User, "NonLGTM'ed PRs", m.Details.NonLGTMed)
+		prRows(m.User, "Created PRs", m.Details.Created)
+		for _, pr := range m.ClosedUnmergedPRs {
+			data = append(data, []string{userDisplayName(m.User), "Closed Unmerged PRs", pr.Repo, "#" + strconv.Itoa(pr.Number),
+				pr.Title, "", "", "", "", pr.URL})
+		}
+	}
+	if len(data) != 0 {
+		table := tablewriter.NewWriter(os.Stdout)
+		fmt.Printf("\n%s\n", title)
+		table.SetHeader([]string{"User Name", "Column", "Repository", "PR/Commit", "Title", "Created At", "Merged At",
+			"LGTM", "Resolved PR", "URL"})
+		table.AppendBulk(data)
+		table.Render()
+	}
+}
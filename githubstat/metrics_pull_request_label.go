@@ -0,0 +1,101 @@
+package githubstat
+
+import (
+	"sort"
+	"strings"
+)
+
+const UnlabeledLabelGroup = "unlabeled"
+
+// LabelBreakdown is the number of PRs by label, only labels starting with one of configured
+// label prefixes are counted, PRs without any of such labels are counted as "unlabeled".
+type LabelBreakdown struct {
+	Merged  map[string]int
+	Created map[string]int
+}
+
+// labelGroupsOf returns lower cased labels starting with any of configured label prefixes,
+// labels differing only in case, e.g. "Kind/Bug" and "kind/bug", are the same group.
+func labelGroupsOf(labelNames []string) []string {
+	var groups []string
+	for _, name := range labelNames {
+		name = strings.ToLower(name)
+		for _, prefix := range Config.LabelPrefixes {
+			if strings.HasPrefix(name, strings.ToLower(prefix)) {
+				if !StringSliceContainsAnyFold(groups, name) {
+					groups = append(groups, name)
+				}
+				break
+			}
+		}
+	}
+	if len(groups) == 0 {
+		groups = append(groups, UnlabeledLabelGroup)
+	}
+	return groups
+}
+
+func (b *LabelBreakdown) addMerged(labelNames []string) {
+	if b.Merged == nil {
+		b.Merged = make(map[string]int)
+	}
+	for _, group := range labelGroupsOf(labelNames) {
+		b.Merged[group]++
+	}
+}
+
+func (b *LabelBreakdown) addCreated(labelNames []string) {
+	if b.Created == nil {
+		b.Created = make(map[string]int)
+	}
+	for _, group := range labelGroupsOf(labelNames) {
+		b.Created[group]++
+	}
+}
+
+// add sums up PRs of another breakdown by label.
+func (b *LabelBreakdown) add(other LabelBreakdown) {
+	merged := make(map[string]int)
+	created := make(map[string]int)
+	for _, m := range []LabelBreakdown{*b, other} {
+		for k, v := range m.Merged {
+			merged[k] += v
+		}
+		for k, v := range m.Created {
+			created[k] += v
+		}
+	}
+	b.Merged = merged
+	b.Created = created
+}
+
+// labelColumns returns labels ordered by name, with "unlabeled" at last.
+func labelColumns(metrics []*PullRequestMetrics, values func(*PullRequestMetrics) map[string]int) []string {
+	found := make(map[string]bool)
+	for _, m := range metrics {
+		for label := range values(m) {
+			found[label] = true
+		}
+	}
+	var columns []string
+	for label := range found {
+		if label != UnlabeledLabelGroup {
+			columns = append(columns, label)
+		}
+	}
+	sort.Strings(columns)
+	if found[UnlabeledLabelGroup] {
+		columns = append(columns, UnlabeledLabelGroup)
+	}
+	return columns
+}
+
+func showLabelBreakdown(metrics []*PullRequestMetrics) {
+	if len(Config.LabelPrefixes) == 0 {
+		return
+	}
+	merged := func(m *PullRequestMetrics) map[string]int { return m.Labels.Merged }
+	showBreakdownTable("Merged PRs by Label", labelColumns(metrics, merged), metrics, merged)
+	created := func(m *PullRequestMetrics) map[string]int { return m.Labels.Created }
+	showBreakdownTable("Created PRs by Label", labelColumns(metrics, created), metrics, created)
+}
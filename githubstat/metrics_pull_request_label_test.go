@@ -0,0 +1,57 @@
+package githubstat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_labelGroupsOf(t *testing.T) {
+	prefixes := Config.LabelPrefixes
+	defer func() { Config.LabelPrefixes = prefixes }()
+	Config.LabelPrefixes = []string{"kind/", "Area/"}
+
+	cases := []struct {
+		labelNames []string
+		groups     []string
+	}{
+		{[]string{"kind/bug"}, []string{"kind/bug"}},
+		{[]string{"Kind/Bug", "area/kubelet"}, []string{"kind/bug", "area/kubelet"}},
+		{[]string{"Kind/Bug", "kind/bug"}, []string{"kind/bug"}},
+		{[]string{"lgtm", "size/XS"}, []string{UnlabeledLabelGroup}},
+		{nil, []string{UnlabeledLabelGroup}},
+	}
+	for _, c := range cases {
+		if groups := labelGroupsOf(c.labelNames); !reflect.DeepEqual(groups, c.groups) {
+			t.Errorf("labelGroupsOf(%v) = %v, want %v", c.labelNames, groups, c.groups)
+		}
+	}
+}
+
+func Test_LabelBreakdown(t *testing.T) {
+	prefixes := Config.LabelPrefixes
+	defer func() { Config.LabelPrefixes = prefixes }()
+	Config.LabelPrefixes = []string{"kind/"}
+
+	var a, b LabelBreakdown
+	a.addMerged([]string{"Kind/Bug"})
+	a.addCreated([]string{"kind/bug", "lgtm"})
+	b.addMerged([]string{"kind/bug"})
+	b.addMerged([]string{"lgtm"})
+	b.addCreated([]string{"kind/feature"})
+
+	shared := a.Merged
+	a.add(b)
+	if want := map[string]int{"kind/bug": 2, UnlabeledLabelGroup: 1}; !reflect.DeepEqual(a.Merged, want) {
+		t.Errorf("merged is %v, want %v", a.Merged, want)
+	}
+	if want := map[string]int{"kind/bug": 1, "kind/feature": 1}; !reflect.DeepEqual(a.Created, want) {
+		t.Errorf("created is %v, want %v", a.Created, want)
+	}
+	if shared["kind/bug"] != 1 {
+		t.Errorf("add modified the shared map, got %v", shared)
+	}
+	metrics := []*PullRequestMetrics{{Labels: a}}
+	if columns := labelColumns(metrics, func(m *PullRequestMetrics) map[string]int { return m.Labels.Merged }); !reflect.DeepEqual(columns, []string{"kind/bug", UnlabeledLabelGroup}) {
+		t.Errorf("columns are %v", columns)
+	}
+}
@@ -0,0 +1,234 @@
+package githubstat
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/olekukonko/tablewriter"
+)
+
+const OtherPathGroup = "other"
+
+var (
+	// languages of well known file extensions, other extensions are shown as they are.
+	ExtensionLanguages = map[string]string{
+		".go":    "Go",
+		".py":    "Python",
+		".sh":    "Shell",
+		".js":    "JavaScript",
+		".ts":    "TypeScript",
+		".java":  "Java",
+		".c":     "C",
+		".h":     "C",
+		".cc":    "C++",
+		".cpp":   "C++",
+		".rb":    "Ruby",
+		".rs":    "Rust",
+		".md":    "Markdown",
+		".html":  "HTML",
+		".css":   "CSS",
+		".scss":  "CSS",
+		".yaml":  "YAML",
+		".yml":   "YAML",
+		".json":  "JSON",
+		".toml":  "TOML",
+		".proto": "Protocol Buffers",
+	}
+)
+
+// PathGroup names a set of files, e.g. "docs" for ["docs/**", "**/*.md"].
+type PathGroup struct {
+	Name     string
+	Patterns []string
+}
+
+// matchPathGlob reports whether file name matches the pattern.
+// besides the syntax of path.Match, "**" matches zero or more directories.
+func matchPathGlob(pattern string, name string) bool {
+	return matchPathSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchPathSegments(patterns []string, names []string) bool {
+	if len(patterns) == 0 {
+		return len(names) == 0
+	}
+	if patterns[0] == "**" {
+		for i := 0; i <= len(names); i++ {
+			if matchPathSegments(patterns[1:], names[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(names) == 0 {
+		return false
+	}
+	if matched, err := path.Match(patterns[0], names[0]); err != nil || !matched {
+		return false
+	}
+	return matchPathSegments(patterns[1:], names[1:])
+}
+
+// pathGroupOf returns name of the first configured path group that file name belongs to.
+func pathGroupOf(name string) string {
+	for _, group := range Config.PathGroups {
+		for _, pattern := range group.Patterns {
+			if matchPathGlob(pattern, name) {
+				return group.Name
+			}
+		}
+	}
+	return OtherPathGroup
+}
+
+func languageOf(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		return "(none)"
+	}
+	if language, found := ExtensionLanguages[ext]; found {
+		return language
+	}
+	return ext
+}
+
+func listPullRequestFiles(client *github.Client, owner string, repo string, number int) ([]*github.CommitFile, error) {
+	opt := &github.ListOptions{PerPage: 100}
+	var allFiles []*github.CommitFile
+	for {
+		files, resp, err := client.PullRequests.ListFiles(owner, repo, number, opt)
+		if err != nil {
+			return nil, err
+		}
+		allFiles = append(allFiles, files...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return allFiles, nil
+}
+
+// PathBreakdown is the changed lines (added + removed) of merged PRs by path group and by language.
+type PathBreakdown struct {
+	Groups    map[string]int
+	Languages map[string]int
+}
+
+func (b *PathBreakdown) addFile(file *github.CommitFile) {
+	if file.Filename == nil {
+		return
+	}
+	var changes int
+	if file.Additions != nil {
+		changes += *file.Additions
+	}
+	if file.Deletions != nil {
+		changes += *file.Deletions
+	}
+	if b.Groups == nil {
+		b.Groups = make(map[string]int)
+		b.Languages = make(map[string]int)
+	}
+	b.Groups[pathGroupOf(*file.Filename)] += changes
+	b.Languages[languageOf(*file.Filename)] += changes
+}
+
+// add sums up changed lines of another breakdown by path group and language.
+func (b *PathBreakdown) add(other PathBreakdown) {
+	groups := make(map[string]int)
+	languages := make(map[string]int)
+	for _, m := range []PathBreakdown{*b, other} {
+		for k, v := range m.Groups {
+			groups[k] += v
+		}
+		for k, v := range m.Languages {
+			languages[k] += v
+		}
+	}
+	b.Groups = groups
+	b.Languages = languages
+}
+
+// getPathBreakdown lists files of a merged PR, it does nothing unless pathBreakdown is enabled
+// because of one more API call per PR.
+func getPathBreakdown(provider Provider, owner string, repo string, number int) PathBreakdown {
+	var breakdown PathBreakdown
+	if !Config.PathBreakdown {
+		return breakdown
+	}
+	files, err := provider.ListFiles(owner, repo, number)
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range files {
+		breakdown.addFile(f)
+	}
+	return breakdown
+}
+
+func showPathBreakdown(metrics []*PullRequestMetrics) {
+	if !Config.PathBreakdown {
+		return
+	}
+	var groups []string
+	for _, group := range Config.PathGroups {
+		groups = append(groups, group.Name)
+	}
+	groups = append(groups, OtherPathGroup)
+	showBreakdownTable("Merged Changes by Path", groups, metrics,
+		func(m *PullRequestMetrics) map[string]int { return m.Paths.Groups })
+
+	totals := make(map[string]int)
+	for _, m := range metrics {
+		for language, changes := range m.Paths.Languages {
+			totals[language] += changes
+		}
+	}
+	var languages []string
+	for language := range totals {
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if totals[languages[i]] != totals[languages[j]] {
+			return totals[languages[i]] > totals[languages[j]]
+		}
+		return languages[i] < languages[j]
+	})
+	showBreakdownTable("Merged Changes by Language", languages, metrics,
+		func(m *PullRequestMetrics) map[string]int { return m.Paths.Languages })
+}
+
+func showBreakdownTable(title string, columns []string, metrics []*PullRequestMetrics, values func(*PullRequestMetrics) map[string]int) {
+	if len(columns) == 0 {
+		return
+	}
+	data := [][]string{}
+	totals := make([]int, len(columns))
+	for _, m := range metrics {
+		r := []string{userDisplayName(m.User)}
+		for i, column := range columns {
+			v := values(m)[column]
+			r = append(r, strconv.Itoa(v))
+			totals[i] += v
+		}
+		data = append(data, r)
+	}
+	if len(data) != 0 {
+		table := tablewriter.NewWriter(os.Stdout)
+		fmt.Printf("\n%s\n", title)
+		table.SetHeader(append([]string{"User Name"}, columns...))
+		table.AppendBulk(data)
+		r := []string{"Total"}
+		for _, v := range totals {
+			r = append(r, strconv.Itoa(v))
+		}
+		table.Append(r)
+		table.Render()
+	}
+}
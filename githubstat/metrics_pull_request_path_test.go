@@ -0,0 +1,26 @@
+package githubstat
+
+import "testing"
+
+func Test_matchPathGlob(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		matched bool
+	}{
+		{"docs/**", "docs/index.md", true},
+		{"docs/**", "docs/user-guide/pods.md", true},
+		{"docs/**", "cmd/docs/main.go", false},
+		{"**/*_test.go", "pkg/api/types_test.go", true},
+		{"**/*_test.go", "main_test.go", true},
+		{"**/*_test.go", "pkg/api/types.go", false},
+		{"pkg/*/types.go", "pkg/api/types.go", true},
+		{"pkg/*/types.go", "pkg/api/v1/types.go", false},
+		{"pkg/**/types.go", "pkg/api/v1/types.go", true},
+	}
+	for _, c := range cases {
+		if matched := matchPathGlob(c.pattern, c.name); matched != c.matched {
+			t.Errorf("matchPathGlob(%q, %q) = %v, want %v", c.pattern, c.name, matched, c.matched)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package githubstat
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/google/go-github/github"
+	"github.com/olekukonko/tablewriter"
+)
+
+var (
+	SizeBucketNames = []string{"XS", "S", "M", "L", "XL"}
+	// upper bounds (excluded) of changed lines for XS, S, M and L PRs, anything bigger is XL
+	DefaultSizeThresholds = []int{10, 30, 100, 500}
+)
+
+// PullRequestSize is the size of merged PRs, counted by lines added/removed and files changed.
+type PullRequestSize struct {
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+	Buckets      [5]int // number of PRs in each size bucket, see SizeBucketNames
+	Unknown      int    // number of PRs without changed lines, which some sources don't fill in
+}
+
+func (s *PullRequestSize) add(other PullRequestSize) {
+	s.Additions += other.Additions
+	s.Deletions += other.Deletions
+	s.ChangedFiles += other.ChangedFiles
+	for i := range s.Buckets {
+		s.Buckets[i] += other.Buckets[i]
+	}
+	s.Unknown += other.Unknown
+}
+
+// sized returns the number of PRs in size buckets.
+func (s *PullRequestSize) sized() int {
+	var n int
+	for _, bucket := range s.Buckets {
+		n += bucket
+	}
+	return n
+}
+
+// sizeBucket returns index of the size bucket that a PR with the given number of changed lines falls into.
+func sizeBucket(changedLines int) int {
+	for i, threshold := range Config.SizeThresholds {
+		if changedLines < threshold {
+			return i
+		}
+	}
+	return len(SizeBucketNames) - 1
+}
+
+// sumPullRequestSizes sums sizes of PRs, PRs without changed lines are counted as unknown instead of XS.
+func sumPullRequestSizes(prs []*github.PullRequest) PullRequestSize {
+	var size PullRequestSize
+	for _, pr := range prs {
+		if pr.Additions == nil || pr.Deletions == nil {
+			size.Unknown++
+			continue
+		}
+		if pr.ChangedFiles != nil {
+			size.ChangedFiles += *pr.ChangedFiles
+		}
+		size.Additions += *pr.Additions
+		size.Deletions += *pr.Deletions
+		size.Buckets[sizeBucket(*pr.Additions+*pr.Deletions)]++
+	}
+	return size
+}
+
+// averagePullRequestSize returns average changed lines per merged PR of known size.
+func averagePullRequestSize(size PullRequestSize) string {
+	sized := size.sized()
+	if sized <= 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(float64(size.Additions+size.Deletions)/float64(sized), 'f', 1, 64)
+}
+
+func showPullRequestSizes(title string, keyHeader string, metrics []*PullRequestMetrics, key func(*PullRequestMetrics) string) {
+	data := [][]string{}
+	var total PullRequestSize
+	for _, m := range metrics {
+		r := []string{key(m), strconv.Itoa(m.Size.Additions), strconv.Itoa(m.Size.Deletions),
+			strconv.Itoa(m.Size.ChangedFiles), averagePullRequestSize(m.Size)}
+		for _, n := range m.Size.Buckets {
+			r = append(r, strconv.Itoa(n))
+		}
+		data = append(data, append(r, strconv.Itoa(m.Size.Unknown)))
+		total.add(m.Size)
+	}
+	if len(data) != 0 {
+		table := tablewriter.NewWriter(os.Stdout)
+		fmt.Printf("\n%s\n", title)
+		table.SetHeader(append([]string{keyHeader, "Lines Added", "Lines Removed", "Changed Files", "Avg PR Size"},
+			append(SizeBucketNames, "Unknown")...))
+		table.AppendBulk(data)
+		r := []string{"Total", strconv.Itoa(total.Additions), strconv.Itoa(total.Deletions),
+			strconv.Itoa(total.ChangedFiles), averagePullRequestSize(total)}
+		for _, n := range total.Buckets {
+			r = append(r, strconv.Itoa(n))
+		}
+		table.Append(append(r, strconv.Itoa(total.Unknown)))
+		table.Render()
+	}
+}
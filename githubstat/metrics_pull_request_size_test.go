@@ -0,0 +1,27 @@
+package githubstat
+
+import (
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func Test_sumPullRequestSizes(t *testing.T) {
+	pr := func(additions, deletions, files int) *github.PullRequest {
+		return &github.PullRequest{Additions: &additions, Deletions: &deletions, ChangedFiles: &files}
+	}
+	prs := []*github.PullRequest{pr(1, 2, 1), pr(20, 9, 2), pr(400, 100, 7), &github.PullRequest{}}
+
+	size := sumPullRequestSizes(prs)
+	if size.Additions != 421 || size.Deletions != 111 || size.ChangedFiles != 10 {
+		t.Errorf("unexpected size: %+v", size)
+	}
+	// 3 lines is XS, 29 lines is S, 500 lines is XL, the PR without size is unknown
+	if size.Buckets != [5]int{1, 1, 0, 0, 1} || size.Unknown != 1 {
+		t.Errorf("unexpected size buckets: %v and %d unknown", size.Buckets, size.Unknown)
+	}
+	// the PR without size is left out of the average
+	if avg := averagePullRequestSize(size); avg != "177.3" {
+		t.Errorf("unexpected average size: %s", avg)
+	}
+}
@@ -0,0 +1,241 @@
+package githubstat
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+// newPullRequestFakeGitHub serves PRs of bruceauyeung and tanshanshan, the stat period is from 2016-10-01
+// to 2016-12-30, and this week begins at 2016-12-24.
+func newPullRequestFakeGitHub(t *testing.T) *fakeGitHub {
+	f := newFakeGitHub(t)
+	// open PRs
+	f.addPullRequest(10, "bruceauyeung", "open", "2016-12-31T00:00:00Z", "2016-12-31T00:00:00Z", "")
+	f.addPullRequest(9, "bruceauyeung", "open", "2016-12-26T00:00:00Z", "2016-12-27T00:00:00Z", "")
+	f.addLabel(9, "lgtm", "2016-12-27T00:00:00Z")
+	f.addPullRequest(8, "tanshanshan", "open", "2016-11-01T00:00:00Z", "2016-11-01T00:00:00Z", "")
+	f.addPullRequest(7, "bruceauyeung", "open", "2016-10-15T00:00:00Z", "2016-10-15T00:00:00Z", "")
+	f.addPullRequest(6, "bruceauyeung", "open", "2016-09-15T00:00:00Z", "2016-09-15T00:00:00Z", "")
+	// closed PRs
+	f.addPullRequest(5, "bruceauyeung", "closed", "2016-12-20T00:00:00Z", "2016-12-25T00:00:00Z", "2016-12-25T00:00:00Z")
+	f.addPullRequest(4, "bruceauyeung", "closed", "2016-09-20T00:00:00Z", "2016-10-10T00:00:00Z", "2016-10-10T00:00:00Z")
+	f.addPullRequest(3, "tanshanshan", "closed", "2016-10-05T00:00:00Z", "2016-11-05T00:00:00Z", "")
+	f.addPullRequest(2, "bruceauyeung", "closed", "2016-08-01T00:00:00Z", "2016-09-10T00:00:00Z", "2016-09-10T00:00:00Z")
+	// commits
+	f.addCommit("aaa", "bruceauyeung", "fix typo", 5)
+	f.addCommit("bbb", "bruceauyeung", "add docs", 4)
+	f.addCommit("ccc", "bruceauyeung", "Merge branch 'master' into add-docs", 4)
+	f.addCommit("ddd", "bruceauyeung", "commit pushed directly", 0)
+	return f
+}
+
+func pullRequestNumbers(prs []*github.PullRequest) []int {
+	var numbers []int
+	for _, pr := range prs {
+		numbers = append(numbers, *pr.Number)
+	}
+	return numbers
+}
+
+func equalInts(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func Test_listOpenPullRequests(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+
+	prs, err := listOpenPullRequests(f.client(), "kubernetes", "kubernetes", configuredPeriod())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numbers := pullRequestNumbers(prs); !equalInts(numbers, []int{9, 8, 7}) {
+		t.Errorf("open PRs are %v, want [9 8 7]", numbers)
+	}
+}
+
+func Test_listClosedPullRequests(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+
+	prs, err := listClosedPullRequests(f.client(), "kubernetes", "kubernetes", configuredPeriod())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numbers := pullRequestNumbers(prs); !equalInts(numbers, []int{5, 3, 4}) {
+		t.Errorf("closed PRs are %v, want [5 3 4]", numbers)
+	}
+}
+
+func Test_findPullRequest(t *testing.T) {
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+
+	pr := findPullRequest(f.client(), "kubernetes", "kubernetes", "bruceauyeung", "aaa")
+	if pr == nil || *pr.Number != 5 || !pr.MergedAt.Equal(*fakeTime("2016-12-25T00:00:00Z")) {
+		t.Errorf("PR of commit aaa is %+v, want #5 merged at 2016-12-25", pr)
+	}
+	if pr := findPullRequest(f.client(), "kubernetes", "kubernetes", "bruceauyeung", "ddd"); pr != nil {
+		t.Errorf("PR of commit ddd is #%d, want none", *pr.Number)
+	}
+}
+
+func Test_LGTM(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+	f.addLabel(7, "kind/bug", "2016-10-15T00:00:00Z")
+	f.addLabel(7, "size/S", "2016-10-15T00:00:00Z")
+	f.addLabel(7, "Docs LGTM", "2016-10-16T00:00:00Z")
+
+	client := f.client()
+	if !isLGTMed(client, "kubernetes", "kubernetes", 9) || !isLGTMed(client, "kubernetes", "kubernetes", 7) {
+		t.Errorf("PR #9 and #7 should be LGTM'ed")
+	}
+	if isLGTMed(client, "kubernetes", "kubernetes", 8) {
+		t.Errorf("PR #8 should not be LGTM'ed")
+	}
+	// LGTM event is on the second page of events
+	event, err := getPullRequestLatestLGTMEvent(client, "kubernetes", "kubernetes", 7, configuredPeriod())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *event.Label.Name != "Docs LGTM" || !event.CreatedAt.Equal(*fakeTime("2016-10-16T00:00:00Z")) {
+		t.Errorf("LGTM event is %s at %v, want Docs LGTM at 2016-10-16", *event.Label.Name, event.CreatedAt)
+	}
+	if _, err := getPullRequestLatestLGTMEvent(client, "kubernetes", "kubernetes", 8, configuredPeriod()); err == nil {
+		t.Errorf("PR #8 should have no LGTM event")
+	}
+}
+
+func Test_FetchMetrics(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	users := Config.Users
+	defer func() { Config.Users = users }()
+	Config.Users = []User{{Name: "bruceauyeung"}, {Name: "tanshanshan"}}
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+
+	var repos []*RepoParameters
+	for _, r := range []string{"kubernetes/kubernetes", "kubernetes/website"} {
+		repo, err := ParseRepo(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repos = append(repos, repo)
+	}
+	m := &PullRequestMetricsRequest{providers: map[string]Provider{"": &restProvider{client: f.client()}}}
+	m.SetParameters(&MetricsParameters{Repos: repos})
+	all := m.FetchMetrics().(*AllPullRequestMetrics)
+
+	// the fake server serves the same PRs for both repos
+	cases := []struct {
+		name    string
+		metrics []*PullRequestMetrics
+		want    []PullRequestMetrics
+	}{
+		{"overall", merge(all.Overall), []PullRequestMetrics{
+			{User: "bruceauyeung", Merged: 4, MergedCommits: 4, LGTMed: 2, NonLGTMed: 2, Created: 6},
+			{User: "tanshanshan", NonLGTMed: 2, Created: 4, ClosedUnmerged: 2},
+		}},
+		{"week", merge(all.Week), []PullRequestMetrics{
+			{User: "bruceauyeung", Merged: 2, MergedCommits: 2, LGTMed: 2, Created: 2},
+			{User: "tanshanshan"},
+		}},
+		{"overall by repo", mergeByRepo(all.Overall), []PullRequestMetrics{
+			{Repo: "kubernetes/kubernetes", Merged: 2, MergedCommits: 2, LGTMed: 1, NonLGTMed: 2, Created: 5, ClosedUnmerged: 1},
+			{Repo: "kubernetes/website", Merged: 2, MergedCommits: 2, LGTMed: 1, NonLGTMed: 2, Created: 5, ClosedUnmerged: 1},
+		}},
+	}
+	for _, c := range cases {
+		if len(c.metrics) != len(c.want) {
+			t.Fatalf("%s metrics has %d rows, want %d", c.name, len(c.metrics), len(c.want))
+		}
+		for i, got := range c.metrics {
+			want := c.want[i]
+			if got.User != want.User || got.Repo != want.Repo && want.Repo != "" || got.Merged != want.Merged ||
+				got.MergedCommits != want.MergedCommits || got.LGTMed != want.LGTMed || got.NonLGTMed != want.NonLGTMed ||
+				got.Created != want.Created || got.ClosedUnmerged != want.ClosedUnmerged {
+				t.Errorf("%s metrics of %s%s is %+v, want %+v", c.name, want.User, want.Repo, *got, want)
+			}
+		}
+	}
+
+	// PRs 5 and 4 of both repos are merged in different weeks
+	trend := merge(all.Overall)[0].Trend
+	if trend.Merged[weekOf(fakeTime("2016-12-25T00:00:00Z"))] != 2 || trend.Merged[weekOf(fakeTime("2016-10-10T00:00:00Z"))] != 2 ||
+		len(trend.Merged) != 2 || len(trend.MergedCommits) != 2 || len(trend.Created) == 0 {
+		t.Errorf("overall trend of bruceauyeung is %+v", trend)
+	}
+
+	// details of both repos are listed in the order of repos
+	details := merge(all.Overall)[0].Details
+	var merged []int
+	for _, pr := range details.Merged {
+		merged = append(merged, pr.Number)
+	}
+	if !equalInts(merged, []int{5, 4, 5, 4}) || details.Merged[0].Repo != "kubernetes/kubernetes" ||
+		details.Merged[0].MergedAt == nil || details.Merged[0].URL != "https://github.com/kubernetes/kubernetes/pull/5" {
+		t.Errorf("details of merged PRs are %+v", details.Merged)
+	}
+	if c := details.MergedCommits[0]; len(details.MergedCommits) != 4 || c.SHA != "aaa" || c.Title != "fix typo" ||
+		c.PullRequest != 5 || c.Resolution != CommitResolutionAuthorSearch || c.URL != "https://github.com/kubernetes/kubernetes/commit/aaa" {
+		t.Errorf("details of merged commits are %+v", details.MergedCommits)
+	}
+	if pr := details.LGTMed[0]; len(details.LGTMed) != 2 || pr.Number != 9 || pr.LGTMLabel != "lgtm" ||
+		pr.LGTMAt == nil || !pr.LGTMAt.Equal(*fakeTime("2016-12-27T00:00:00Z")) {
+		t.Errorf("details of LGTM'ed PRs are %+v", details.LGTMed)
+	}
+	if len(details.NonLGTMed) != 2 || len(details.Created) != 6 {
+		t.Errorf("details of NonLGTM'ed and created PRs are %+v and %+v", details.NonLGTMed, details.Created)
+	}
+	if week := merge(all.Week)[0].Details; len(week.Merged) != 2 || len(week.MergedCommits) != 2 || week.Merged[0].Number != 5 {
+		t.Errorf("details of this week are %+v", week)
+	}
+}
+
+func Test_fixtureTransport(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	dir, err := ioutil.TempDir("", "githubstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f := newPullRequestFakeGitHub(t)
+	baseURL := f.URL + "/"
+
+	client := github.NewClient(&http.Client{Transport: newFixtureTransport(FixtureModeRecord, dir, http.DefaultTransport)})
+	client.BaseURL = mustParseURL(baseURL)
+	recorded, err := listClosedPullRequests(client, "kubernetes", "kubernetes", configuredPeriod())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// replay without the server
+	f.Close()
+	client = github.NewClient(&http.Client{Transport: newFixtureTransport(FixtureModeReplay, dir, nil)})
+	client.BaseURL = mustParseURL(baseURL)
+	replayed, err := listClosedPullRequests(client, "kubernetes", "kubernetes", configuredPeriod())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := pullRequestNumbers(recorded), pullRequestNumbers(replayed); !equalInts(a, b) || len(a) != 3 {
+		t.Errorf("replayed PRs are %v, recorded PRs are %v", b, a)
+	}
+	if _, err := listOpenPullRequests(client, "kubernetes", "kubernetes", configuredPeriod()); err == nil {
+		t.Errorf("requests which were not recorded should fail")
+	}
+}
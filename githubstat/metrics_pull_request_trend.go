@@ -0,0 +1,68 @@
+package githubstat
+
+import (
+	"time"
+)
+
+// WeeklyTrend is the number of merged PRs, merged commits and created PRs by week,
+// weeks are keyed by their first day of format "2006-01-02".
+type WeeklyTrend struct {
+	Merged        map[string]int
+	MergedCommits map[string]int
+	Created       map[string]int
+}
+
+func weekOf(t *time.Time) string {
+	return getWeekFirstDay(t.In(time.Local)).Format("2006-01-02")
+}
+
+func (w *WeeklyTrend) addMerged(t *time.Time) {
+	if w.Merged == nil {
+		w.Merged = make(map[string]int)
+	}
+	w.Merged[weekOf(t)]++
+}
+
+func (w *WeeklyTrend) addMergedCommit(t *time.Time) {
+	if w.MergedCommits == nil {
+		w.MergedCommits = make(map[string]int)
+	}
+	w.MergedCommits[weekOf(t)]++
+}
+
+func (w *WeeklyTrend) addCreated(t *time.Time) {
+	if w.Created == nil {
+		w.Created = make(map[string]int)
+	}
+	w.Created[weekOf(t)]++
+}
+
+// add sums up another trend week by week.
+func (w *WeeklyTrend) add(other WeeklyTrend) {
+	merged := make(map[string]int)
+	mergedCommits := make(map[string]int)
+	created := make(map[string]int)
+	for _, t := range []WeeklyTrend{*w, other} {
+		for k, v := range t.Merged {
+			merged[k] += v
+		}
+		for k, v := range t.MergedCommits {
+			mergedCommits[k] += v
+		}
+		for k, v := range t.Created {
+			created[k] += v
+		}
+	}
+	w.Merged = merged
+	w.MergedCommits = mergedCommits
+	w.Created = created
+}
+
+// trendWeeks returns first days of all weeks from the week of begin to the week of end in order.
+func trendWeeks(begin time.Time, end time.Time) []string {
+	var weeks []string
+	for week := getWeekFirstDay(begin.In(time.Local)); week.Before(end); week = week.AddDate(0, 0, 7) {
+		weeks = append(weeks, week.Format("2006-01-02"))
+	}
+	return weeks
+}
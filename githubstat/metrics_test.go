@@ -0,0 +1,28 @@
+package githubstat
+
+import "testing"
+
+func Test_ParseRepo(t *testing.T) {
+	cases := []struct {
+		repoStr string
+		host    string
+		str     string
+	}{
+		{"kubernetes/kubernetes", "", "kubernetes/kubernetes"},
+		{"github.com:kubernetes/*", "", "kubernetes/*"},
+		{"ghe.corp:team/repo", "ghe.corp", "ghe.corp:team/repo"},
+		{"gitlab.com:group/subgroup/project", "gitlab.com", "gitlab.com:group/subgroup/project"},
+	}
+	for _, c := range cases {
+		repo, err := ParseRepo(c.repoStr)
+		if err != nil {
+			t.Fatalf("ParseRepo(%q) failed: %v", c.repoStr, err)
+		}
+		if repo.Host != c.host || repo.String() != c.str {
+			t.Errorf("ParseRepo(%q) = %q on host %q, want %q on host %q", c.repoStr, repo, repo.Host, c.str, c.host)
+		}
+	}
+	if _, err := ParseRepo("kubernetes"); err == nil {
+		t.Errorf("ParseRepo should fail without owner name")
+	}
+}
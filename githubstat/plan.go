@@ -0,0 +1,323 @@
+package githubstat
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/olekukonko/tablewriter"
+)
+
+// defaultCallLatency is used to estimate duration if no API call is sampled.
+const defaultCallLatency = 300 * time.Millisecond
+
+// Plan is an estimate of API calls that fetching metrics of repos makes, which is computed from the first page of
+// PRs and commits of every repo, so that quotas are checked before an expensive run.
+// calls are estimated for the REST api, and they are upper bounds, e.g. every open PR is assumed to be LGTM'ed.
+type Plan struct {
+	Repos []*RepoPlan
+	// quotas by host name, nil if the host has no rate limit
+	Quotas map[string]*github.RateLimits
+
+	sampleCalls int
+	sampleTime  time.Duration
+}
+
+type RepoPlan struct {
+	Repo              string
+	Host              string
+	OpenPRs           int // open PRs of users created in stat period
+	MergedPRs         int // PRs of users merged in stat period
+	ClosedUnmergedPRs int // PRs of users closed unmerged in stat period
+	Commits           int // commits of users whose PRs are searched
+	RESTCalls         int
+	SearchCalls       int
+}
+
+// EstimatePlan resolves repos and estimates API calls of fetching their metrics without fetching them.
+func EstimatePlan(repos []*RepoParameters) *Plan {
+	m := &PullRequestMetricsRequest{param: &MetricsParameters{Repos: repos}}
+	return m.plan()
+}
+
+func (m *PullRequestMetricsRequest) plan() *Plan {
+	plan := &Plan{Quotas: make(map[string]*github.RateLimits)}
+	m.expandRepos()
+	for _, repo := range m.param.Repos {
+		client := restClientOf(m.providerOf(repo.Host))
+		if client == nil {
+			fmt.Printf("%s : no API call is made by source or host\n", repo)
+			continue
+		}
+		if _, found := plan.Quotas[repo.Host]; !found {
+			rates, _, err := client.RateLimits()
+			if err != nil {
+				// rate limiting may be disabled on GitHub Enterprise
+				fmt.Printf("%s : failed to get rate limits : %v\n", repo, err)
+			}
+			plan.Quotas[repo.Host] = rates
+		}
+		fmt.Printf("%s : sampling pull requests and commits\n", repo)
+		repoPlan, err := plan.sampleRepo(client, repo)
+		if err != nil {
+			panic(err)
+		}
+		plan.Repos = append(plan.Repos, repoPlan)
+	}
+	return plan
+}
+
+// restClientOf returns the client of REST api that provider fetches PRs with, nil if provider makes no call to GitHub.
+func restClientOf(provider Provider) *github.Client {
+	switch p := provider.(type) {
+	case *restProvider:
+		return p.client
+	case *graphqlProvider:
+		return p.client
+	}
+	return nil
+}
+
+// sampleRepo estimates calls of FetchMetrics and getStackalyticsCommits from the first page of PRs and commits.
+func (plan *Plan) sampleRepo(client *github.Client, repo *RepoParameters) (*RepoPlan, error) {
+	ownerName := *repo.OwnerName
+	repoName := *repo.RepoName
+	repoPlan := &RepoPlan{Repo: repo.String(), Host: repo.Host}
+
+	openPRs, openFactor, err := plan.samplePullRequests(client, ownerName, repoName, "open", "created",
+		configuredPeriod().filterOpen, func(pr *github.PullRequest) *time.Time { return pr.CreatedAt })
+	if err != nil {
+		return nil, err
+	}
+	closedPRs, closedFactor, err := plan.samplePullRequests(client, ownerName, repoName, "closed", "updated",
+		configuredPeriod().filterClosed, func(pr *github.PullRequest) *time.Time { return pr.UpdatedAt })
+	if err != nil {
+		return nil, err
+	}
+	var open, merged, closedUnmerged, closed, commits, rest, search float64
+	rest += math.Ceil(openFactor) + math.Ceil(closedFactor)
+	for _, user := range Config.Users {
+		login := user.login(repo.Host)
+		open += float64(len(filterByUserName(openPRs, login))) * openFactor
+		for _, pr := range filterByUserName(closedPRs, login) {
+			closed += closedFactor
+			if pr.MergedAt != nil && configuredPeriod().contains(pr.MergedAt) {
+				merged += closedFactor
+			} else if pr.MergedAt == nil && pr.ClosedAt != nil && configuredPeriod().contains(pr.ClosedAt) {
+				closedUnmerged += closedFactor
+			}
+		}
+		userCommits, pages, err := plan.sampleCommits(client, ownerName, repoName, login)
+		if err != nil {
+			return nil, err
+		}
+		commits += userCommits
+		// a search for PR of every commit, and getting the PR found
+		rest += float64(pages) + userCommits
+		search += userCommits
+	}
+	// labels and LGTM event of every open PR
+	rest += 2 * open
+	// PR with all fields of every merged PR
+	rest += merged
+	if Config.PathBreakdown {
+		// changed files of every merged PR
+		rest += merged
+	}
+	if len(Config.LabelPrefixes) != 0 {
+		// labels of every closed PR
+		rest += closed
+	}
+	if Config.ListClosedUnmerged {
+		// who closed every closed unmerged PR
+		rest += closedUnmerged
+	}
+
+	repoPlan.OpenPRs = int(math.Ceil(open))
+	repoPlan.MergedPRs = int(math.Ceil(merged))
+	repoPlan.ClosedUnmergedPRs = int(math.Ceil(closedUnmerged))
+	repoPlan.Commits = int(math.Ceil(commits))
+	repoPlan.RESTCalls = int(math.Ceil(rest))
+	repoPlan.SearchCalls = int(math.Ceil(search))
+	return repoPlan, nil
+}
+
+// samplePullRequests lists the first page of PRs, and returns PRs of the page kept by filter and how many pages
+// are listed. PRs are listed until stat begin time, so pages are extrapolated from the time span of the first page.
+func (plan *Plan) samplePullRequests(client *github.Client, owner string, repo string, state string, sort string,
+	filter func(*github.PullRequest) (bool, bool), at func(*github.PullRequest) *time.Time) ([]*github.PullRequest, float64, error) {
+	opt := &github.PullRequestListOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+		State:       state,
+		Sort:        sort,
+		Direction:   "desc",
+	}
+	start := time.Now()
+	prs, resp, err := client.PullRequests.List(owner, repo, opt)
+	plan.sampleCalls++
+	plan.sampleTime += time.Since(start)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var kept []*github.PullRequest
+	stopped := false
+	for _, pr := range prs {
+		keep, stop := filter(pr)
+		if stop {
+			stopped = true
+			break
+		}
+		if keep {
+			kept = append(kept, pr)
+		}
+	}
+	if stopped || resp.NextPage == 0 || len(prs) < 2 {
+		return kept, 1, nil
+	}
+	pages := float64(resp.LastPage)
+	first, last := at(prs[0]), at(prs[len(prs)-1])
+	if span := first.Sub(*last); span > 0 {
+		extrapolated := float64(first.Sub(Config.StatBeginTime)) / float64(span)
+		if pages == 0 || extrapolated < pages {
+			pages = extrapolated
+		}
+	} else if pages == 0 {
+		// neither the last page nor a time span is known, at least the next page is listed
+		pages = float64(resp.NextPage)
+	}
+	return kept, math.Max(pages, 1), nil
+}
+
+// sampleCommits lists the first page of commits of author, and returns the number of commits and pages listed
+// by getStackalyticsCommits, merge commits are not counted because no PR is searched for them.
+func (plan *Plan) sampleCommits(client *github.Client, owner string, repo string, author string) (float64, int, error) {
+	opt := &github.CommitsListOptions{
+		Author:      author,
+		Until:       Config.StatEndTime,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	start := time.Now()
+	commits, resp, err := client.Repositories.ListCommits(owner, repo, opt)
+	plan.sampleCalls++
+	plan.sampleTime += time.Since(start)
+	if err != nil {
+		if strings.Contains(err.Error(), "409") && strings.Contains(err.Error(), "Git Repository is empty") {
+			return 0, 1, nil
+		}
+		return 0, 0, err
+	}
+	pages := 1
+	if resp.NextPage != 0 {
+		pages = resp.LastPage
+		if pages == 0 {
+			pages = resp.NextPage
+		}
+	}
+	return float64(len(filterCommits(commits)) * pages), pages, nil
+}
+
+// callLatency is the average duration of sampled API calls.
+func (plan *Plan) callLatency() time.Duration {
+	if plan.sampleCalls == 0 {
+		return defaultCallLatency
+	}
+	return plan.sampleTime / time.Duration(plan.sampleCalls)
+}
+
+// quotaWait returns how long calls wait for quota to be reset, quota is reset every window.
+func quotaWait(calls int, rate *github.Rate, window time.Duration) time.Duration {
+	if rate == nil || rate.Limit == 0 || calls <= rate.Remaining {
+		return 0
+	}
+	windows := (calls - rate.Remaining - 1) / rate.Limit
+	wait := time.Until(rate.Reset.Time)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait + time.Duration(windows)*window
+}
+
+// EstimatedDuration is the longest of making all calls one by one, and waiting for REST and search quotas.
+func (plan *Plan) EstimatedDuration() time.Duration {
+	restCalls := make(map[string]int)
+	searchCalls := make(map[string]int)
+	var calls int
+	for _, r := range plan.Repos {
+		restCalls[r.Host] += r.RESTCalls
+		searchCalls[r.Host] += r.SearchCalls
+		calls += r.RESTCalls + r.SearchCalls
+	}
+	duration := time.Duration(calls) * plan.callLatency()
+	for host, rates := range plan.Quotas {
+		if rates == nil {
+			continue
+		}
+		if wait := quotaWait(restCalls[host], rates.Core, time.Hour); wait > duration {
+			duration = wait
+		}
+		if wait := quotaWait(searchCalls[host], rates.Search, time.Minute); wait > duration {
+			duration = wait
+		}
+	}
+	return duration
+}
+
+func (plan *Plan) Show() {
+	data := [][]string{}
+	var total RepoPlan
+	restCalls := make(map[string]int)
+	searchCalls := make(map[string]int)
+	var hosts []string
+	for _, r := range plan.Repos {
+		data = append(data, []string{r.Repo, strconv.Itoa(r.OpenPRs), strconv.Itoa(r.MergedPRs),
+			strconv.Itoa(r.ClosedUnmergedPRs), strconv.Itoa(r.Commits), strconv.Itoa(r.RESTCalls), strconv.Itoa(r.SearchCalls)})
+		total.OpenPRs += r.OpenPRs
+		total.MergedPRs += r.MergedPRs
+		total.ClosedUnmergedPRs += r.ClosedUnmergedPRs
+		total.Commits += r.Commits
+		total.RESTCalls += r.RESTCalls
+		total.SearchCalls += r.SearchCalls
+		if _, found := restCalls[r.Host]; !found {
+			hosts = append(hosts, r.Host)
+		}
+		restCalls[r.Host] += r.RESTCalls
+		searchCalls[r.Host] += r.SearchCalls
+	}
+	if len(data) == 0 {
+		return
+	}
+	fmt.Printf("\nEstimated API Calls ( sampled by %d calls in %v)\n", plan.sampleCalls, plan.sampleTime)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Repository", "Open PRs", "Merged PRs", "Closed Unmerged PRs", "Commits",
+		"REST Calls", "Search Calls"})
+	table.AppendBulk(data)
+	table.Append([]string{"Total", strconv.Itoa(total.OpenPRs), strconv.Itoa(total.MergedPRs),
+		strconv.Itoa(total.ClosedUnmergedPRs), strconv.Itoa(total.Commits), strconv.Itoa(total.RESTCalls),
+		strconv.Itoa(total.SearchCalls)})
+	table.Render()
+
+	for _, host := range hosts {
+		name := host
+		if name == "" {
+			name = DefaultHost
+		}
+		rates := plan.Quotas[host]
+		if rates == nil || rates.Core == nil || rates.Search == nil {
+			fmt.Printf("%s : %d REST calls, %d search calls, rate limits are unknown\n", name, restCalls[host], searchCalls[host])
+			continue
+		}
+		fmt.Printf("%s : %d REST calls of %d remaining (%d per hour, reset at %v)\n", name, restCalls[host],
+			rates.Core.Remaining, rates.Core.Limit, rates.Core.Reset.Time)
+		fmt.Printf("%s : %d search calls of %d remaining (%d per minute, reset at %v)\n", name, searchCalls[host],
+			rates.Search.Remaining, rates.Search.Limit, rates.Search.Reset.Time)
+		if restCalls[host] > rates.Core.Remaining {
+			fmt.Printf("warning: REST quota of %s is exhausted partway, fetching waits for quota to be reset\n", name)
+		}
+	}
+	fmt.Printf("estimated duration : %v (%v per call)\n", plan.EstimatedDuration(), plan.callLatency())
+}
@@ -0,0 +1,85 @@
+package githubstat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func Test_plan(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	users := Config.Users
+	defer func() { Config.Users = users }()
+	Config.Users = []User{{Name: "bruceauyeung"}, {Name: "tanshanshan"}}
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+
+	repo, err := ParseRepo("kubernetes/kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &PullRequestMetricsRequest{param: &MetricsParameters{Repos: []*RepoParameters{repo}},
+		providers: map[string]Provider{"": &restProvider{client: f.client()}}}
+	plan := m.plan()
+	if len(plan.Repos) != 1 {
+		t.Fatalf("plan has %d repos, want 1", len(plan.Repos))
+	}
+	// open PRs are extrapolated to the last page, closed PRs to 1.7 pages by update time of the first page,
+	// and commits of bruceauyeung are 2 non-merge commits of 2 pages
+	want := RepoPlan{Repo: "kubernetes/kubernetes", OpenPRs: 3, MergedPRs: 2, ClosedUnmergedPRs: 2, Commits: 4,
+		RESTCalls: 20, SearchCalls: 4}
+	if got := *plan.Repos[0]; got != want {
+		t.Errorf("plan of repo is %+v, want %+v", got, want)
+	}
+	// 2 PR pages, 2 commit pages and rate limits
+	if len(f.requests) != 5 {
+		t.Errorf("plan made requests %v, want 5", f.requests)
+	}
+	if rates := plan.Quotas[""]; rates == nil || rates.Core.Remaining != 4999 || rates.Search.Limit != 30 {
+		t.Errorf("quotas are %+v, want 4999 REST calls and 30 search calls remaining", rates)
+	}
+}
+
+func Test_samplePullRequestsWithoutLastPage(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	f := newFakeGitHub(t)
+	defer f.Close()
+	f.noLastPage = true
+	// PRs of the first page are created at the same time
+	f.addPullRequest(3, "bruceauyeung", "open", "2016-12-01T00:00:00Z", "2016-12-01T00:00:00Z", "")
+	f.addPullRequest(2, "bruceauyeung", "open", "2016-12-01T00:00:00Z", "2016-12-01T00:00:00Z", "")
+	f.addPullRequest(1, "bruceauyeung", "open", "2016-11-01T00:00:00Z", "2016-11-01T00:00:00Z", "")
+
+	createdAt := func(pr *github.PullRequest) *time.Time { return pr.CreatedAt }
+	plan := &Plan{}
+	if _, pages, err := plan.samplePullRequests(f.client(), "kubernetes", "kubernetes", "open", "created",
+		configuredPeriod().filterOpen, createdAt); err != nil || pages != 2 {
+		t.Errorf("PRs without time span nor last page are listed in %v pages, %v, want 2", pages, err)
+	}
+	// the first page spans 30 days of 91 days since stat begin time
+	f.pullRequests[1].CreatedAt = fakeTime("2016-11-01T00:00:00Z")
+	f.pullRequests[0].CreatedAt = fakeTime("2016-12-01T00:00:00Z")
+	if _, pages, err := plan.samplePullRequests(f.client(), "kubernetes", "kubernetes", "open", "created",
+		configuredPeriod().filterOpen, createdAt); err != nil || pages < 2 || pages > 2.1 {
+		t.Errorf("PRs without last page are listed in %v pages, %v, want 61/30", pages, err)
+	}
+}
+
+func Test_quotaWait(t *testing.T) {
+	rate := &github.Rate{Limit: 30, Remaining: 10, Reset: github.Timestamp{Time: time.Now().Add(time.Minute)}}
+	cases := []struct {
+		calls int
+		min   time.Duration
+		max   time.Duration
+	}{
+		{10, 0, 0},
+		{40, 50 * time.Second, time.Minute},
+		{41, 110 * time.Second, 2 * time.Minute},
+	}
+	for _, c := range cases {
+		if wait := quotaWait(c.calls, rate, time.Minute); wait < c.min || wait > c.max {
+			t.Errorf("%d calls wait %v, want between %v and %v", c.calls, wait, c.min, c.max)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package githubstat
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// getRateLimits gets API quotas of GitHub hosts of repos, hosts without rate limits are left out.
+func getRateLimits(repos []*RepoParameters) map[string]*github.RateLimits {
+	quotas := make(map[string]*github.RateLimits)
+	if Config.Source != "" && Config.Source != SourceAPI {
+		return quotas
+	}
+	for _, repo := range repos {
+		if _, found := quotas[repo.Host]; found {
+			continue
+		}
+		proxyClient := getProxyClient(repo.Host)
+		if t := proxyClient.getHost().Type; t != "" && t != HostTypeGitHub {
+			continue
+		}
+		rates, _, err := proxyClient.getClient().RateLimits()
+		if err != nil {
+			fmt.Printf("failed to get rate limits of %s : %v\n", proxyClient.getHost().Name, err)
+			continue
+		}
+		quotas[repo.Host] = rates
+	}
+	return quotas
+}
+
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// prometheusWriter writes metrics in the text exposition format of Prometheus.
+type prometheusWriter struct {
+	bytes.Buffer
+}
+
+// family writes help and type of a metric family, which precede its samples.
+func (p *prometheusWriter) family(name string, help string) {
+	fmt.Fprintf(p, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+// sample writes a sample of the metric, labels are pairs of label name and value.
+func (p *prometheusWriter) sample(name string, value float64, labels ...string) {
+	p.WriteString(name)
+	if len(labels) != 0 {
+		p.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i != 0 {
+				p.WriteString(",")
+			}
+			fmt.Fprintf(p, `%s="%s"`, labels[i], prometheusLabelEscaper.Replace(labels[i+1]))
+		}
+		p.WriteString("}")
+	}
+	fmt.Fprintf(p, " %g\n", value)
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	all, quotas, refreshedAt, fetchDuration := s.metrics, s.quotas, s.refreshedAt, s.fetchDuration
+	s.mu.RUnlock()
+	if all == nil {
+		http.Error(w, "metrics are not fetched yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	var windows []string
+	for _, window := range []string{WindowOverall, WindowWeek} {
+		if !weekDisabled(window) {
+			windows = append(windows, window)
+		}
+	}
+	p := &prometheusWriter{}
+	gauges := []struct {
+		name  string
+		help  string
+		value func(*PullRequestMetrics) int
+	}{
+		{"github_contrib_merged_prs", "PRs of the user merged in the window.",
+			func(m *PullRequestMetrics) int { return m.Merged }},
+		{"github_contrib_merged_commits", "Commits of the user merged in the window, in stackalytics.com's style.",
+			func(m *PullRequestMetrics) int { return m.MergedCommits }},
+		{"github_contrib_created_prs", "PRs of the user created in the window.",
+			func(m *PullRequestMetrics) int { return m.Created }},
+		{"github_contrib_closed_unmerged_prs", "PRs of the user closed without being merged in the window.",
+			func(m *PullRequestMetrics) int { return m.ClosedUnmerged }},
+	}
+	for _, g := range gauges {
+		p.family(g.name, g.help)
+		for _, window := range windows {
+			for _, m := range windowMetrics(all, window) {
+				p.sample(g.name, float64(g.value(m)), "user", m.User, "repo", m.Repo, "window", window)
+			}
+		}
+	}
+	p.family("github_contrib_open_prs", "Open PRs of the user, LGTM'ed ones in the week are those LGTM'ed this week.")
+	for _, window := range windows {
+		for _, m := range windowMetrics(all, window) {
+			p.sample("github_contrib_open_prs", float64(m.LGTMed), "user", m.User, "repo", m.Repo, "window", window, "lgtm", "true")
+			p.sample("github_contrib_open_prs", float64(m.NonLGTMed), "user", m.User, "repo", m.Repo, "window", window, "lgtm", "false")
+		}
+	}
+
+	var hosts []string
+	for host := range quotas {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	quotaGauges := []struct {
+		name  string
+		help  string
+		value func(*github.Rate) float64
+	}{
+		{"github_contrib_api_quota_remaining", "Remaining API calls of the host after the latest refresh.",
+			func(r *github.Rate) float64 { return float64(r.Remaining) }},
+		{"github_contrib_api_quota_limit", "API calls of the host allowed in a rate limit window.",
+			func(r *github.Rate) float64 { return float64(r.Limit) }},
+	}
+	for _, g := range quotaGauges {
+		p.family(g.name, g.help)
+		for _, host := range hosts {
+			rates := quotas[host]
+			if rates == nil {
+				continue
+			}
+			name := host
+			if name == "" {
+				name = DefaultHost
+			}
+			if rate := rates.Core; rate != nil {
+				p.sample(g.name, g.value(rate), "host", name, "resource", "core")
+			}
+			if rate := rates.Search; rate != nil {
+				p.sample(g.name, g.value(rate), "host", name, "resource", "search")
+			}
+		}
+	}
+	p.family("github_contrib_fetch_duration_seconds", "Duration of the latest refresh of metrics.")
+	p.sample("github_contrib_fetch_duration_seconds", fetchDuration.Seconds())
+	p.family("github_contrib_last_refresh_timestamp_seconds", "Unix time of the latest refresh of metrics.")
+	p.sample("github_contrib_last_refresh_timestamp_seconds", float64(refreshedAt.Unix()))
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(p.Bytes())
+}
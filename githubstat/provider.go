@@ -0,0 +1,201 @@
+package githubstat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	APIREST    = "rest"
+	APIGraphQL = "graphql"
+
+	HostTypeGitHub = "github"
+	HostTypeGitLab = "gitlab"
+	HostTypeGitea  = "gitea" // Gitea and Forgejo
+
+	SourceAPI   = "api"
+	SourceGit   = "git"   // local clones
+	SourceStore = "store" // local store filled by sync
+)
+
+// Provider fetches pull requests and related data that pull request metrics are computed from.
+// types of go-github are used as the common representation of data from all providers.
+type Provider interface {
+	// ListRepositories lists repositories of an owner (user, organization or group).
+	ListRepositories(owner string) ([]*github.Repository, error)
+	// ListOpenPullRequests lists open PRs created in stat period.
+	ListOpenPullRequests(owner string, repo string) ([]*github.PullRequest, error)
+	// ListClosedPullRequests lists closed PRs merged, closed or created in stat period.
+	ListClosedPullRequests(owner string, repo string) ([]*github.PullRequest, error)
+	// ListUpdatedPullRequests lists open and closed PRs updated since the given time, by update time descendingly.
+	ListUpdatedPullRequests(owner string, repo string, since time.Time) ([]*github.PullRequest, error)
+	// GetPullRequest gets a PR with all fields filled in, such as Commits, Additions and Deletions.
+	GetPullRequest(owner string, repo string, number int) (*github.PullRequest, error)
+	GetLabelNames(owner string, repo string, number int) ([]string, error)
+	// GetLatestLGTMEvent gets the event that a LGTM label was added to a PR.
+	GetLatestLGTMEvent(owner string, repo string, number int) (*github.IssueEvent, error)
+	ListFiles(owner string, repo string, number int) ([]*github.CommitFile, error)
+	// GetClosedBy gets login of the user who closed a PR.
+	GetClosedBy(owner string, repo string, number int) (string, error)
+	// ListMergedCommits lists commits of author merged in stat period, in stackalytics.com's style.
+	ListMergedCommits(owner string, repo string, author string) ([]*PullRequestCommit, error)
+}
+
+// newProvider creates a provider listing PRs and commits in the period according to type of the host and api of
+// GitHub hosts, the configured api is used if api is empty, and the configured stat period if period is zero.
+func newProvider(proxyClient *ProxyClient, api string, period statPeriod) Provider {
+	host := proxyClient.getHost()
+	switch host.Type {
+	case "", HostTypeGitHub:
+	case HostTypeGitLab:
+		return newGitLabProvider(proxyClient, period)
+	case HostTypeGitea:
+		return newGiteaProvider(proxyClient, period)
+	default:
+		panic(fmt.Sprintf("unknown type of host %s : %s, must be %q, %q or %q", host.Name, host.Type,
+			HostTypeGitHub, HostTypeGitLab, HostTypeGitea))
+	}
+	if api == "" {
+		api = Config.API
+	}
+	switch api {
+	case "", APIREST:
+		return &restProvider{client: proxyClient.getClient(), period: period}
+	case APIGraphQL:
+		return newGraphQLProvider(proxyClient, period)
+	default:
+		panic(fmt.Sprintf("unknown api : %s, must be %q or %q", api, APIREST, APIGraphQL))
+	}
+}
+
+// pullRequestKey returns "owner/repo#number", which is used as key of PRs cached by providers.
+func pullRequestKey(owner string, repo string, number int) string {
+	return fmt.Sprintf("%s/%s#%d", owner, repo, number)
+}
+
+// getJSON gets url and decodes json response into v.
+func getJSON(httpClient *http.Client, url string, v interface{}) (*http.Response, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp, &statusError{url: url, status: resp.Status, code: resp.StatusCode}
+	}
+	return resp, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// statusError is the error of a response whose status is not 200 OK.
+type statusError struct {
+	url    string
+	status string
+	code   int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("GET %s: %s", e.url, e.status)
+}
+
+// isNotFound reports whether err is caused by a 404 response.
+func isNotFound(err error) bool {
+	e, ok := err.(*statusError)
+	return ok && e.code == http.StatusNotFound
+}
+
+// restProvider fetches data by GitHub REST API (v3), it makes several requests per PR.
+type restProvider struct {
+	client *github.Client
+	period statPeriod // period which PRs and commits are listed in, the configured one if it is zero
+}
+
+// ListRepositories lists repositories of an organization including private ones, or repositories owned by a user.
+func (p *restProvider) ListRepositories(owner string) ([]*github.Repository, error) {
+	user, _, err := p.client.Users.Get(owner)
+	if err != nil {
+		return nil, err
+	}
+	if user.Type != nil && *user.Type == "Organization" {
+		return listOrgRepositories(p.client, owner, &github.RepositoryListByOrgOptions{
+			Type:        "all",
+			ListOptions: github.ListOptions{PerPage: 100}})
+	}
+	return listRepositories(p.client, owner, &github.RepositoryListOptions{
+		Type:        "owner",
+		ListOptions: github.ListOptions{PerPage: 100}})
+}
+
+func (p *restProvider) ListOpenPullRequests(owner string, repo string) ([]*github.PullRequest, error) {
+	return listOpenPullRequests(p.client, owner, repo, p.period.orConfigured())
+}
+
+func (p *restProvider) ListClosedPullRequests(owner string, repo string) ([]*github.PullRequest, error) {
+	return listClosedPullRequests(p.client, owner, repo, p.period.orConfigured())
+}
+
+func (p *restProvider) ListUpdatedPullRequests(owner string, repo string, since time.Time) ([]*github.PullRequest, error) {
+	return listUpdatedPullRequests(p.client, owner, repo, since)
+}
+
+func (p *restProvider) GetPullRequest(owner string, repo string, number int) (*github.PullRequest, error) {
+	return getPullRequest(p.client, owner, repo, number)
+}
+
+func (p *restProvider) GetLabelNames(owner string, repo string, number int) ([]string, error) {
+	return getPullRequestLabelNames(p.client, owner, repo, number), nil
+}
+
+func (p *restProvider) GetLatestLGTMEvent(owner string, repo string, number int) (*github.IssueEvent, error) {
+	return getPullRequestLatestLGTMEvent(p.client, owner, repo, number, p.period.orConfigured())
+}
+
+func (p *restProvider) ListFiles(owner string, repo string, number int) ([]*github.CommitFile, error) {
+	return listPullRequestFiles(p.client, owner, repo, number)
+}
+
+func (p *restProvider) GetClosedBy(owner string, repo string, number int) (string, error) {
+	issue := getIssue(p.client, owner, repo, number)
+	if issue.ClosedBy != nil && issue.ClosedBy.Login != nil {
+		return *issue.ClosedBy.Login, nil
+	}
+	return "", nil
+}
+
+func (p *restProvider) ListMergedCommits(owner string, repo string, author string) ([]*PullRequestCommit, error) {
+	return getStackalyticsCommits(p.client, owner, repo, author, "", p.period.orConfigured()), nil
+}
+
+// ListMergedCommitsSince lists merged commits of author newer than the commit lastSHA,
+// so that PRs of commits synced before are not searched again.
+func (p *restProvider) ListMergedCommitsSince(owner string, repo string, author string, lastSHA string) ([]*PullRequestCommit, error) {
+	return getStackalyticsCommits(p.client, owner, repo, author, lastSHA, p.period.orConfigured()), nil
+}
+
+// loginByEmail returns login on the host of the configured user who owns the email, or the email itself if nobody does.
+func loginByEmail(host string, email string) string {
+	for _, u := range Config.Users {
+		for _, e := range u.Emails {
+			if strings.EqualFold(e, email) {
+				return u.login(host)
+			}
+		}
+	}
+	return email
+}
+
+// authoredBy reports whether a commit is authored by the user of the login on the host, by the account which
+// the host links the commit to, or by emails of configured users if it is linked to none.
+func authoredBy(host string, c *github.RepositoryCommit, login string) bool {
+	if c.Author != nil && c.Author.Login != nil && *c.Author.Login != "" {
+		return strings.EqualFold(*c.Author.Login, login)
+	}
+	if c.Commit == nil || c.Commit.Author == nil || c.Commit.Author.Email == nil {
+		return false
+	}
+	return loginByEmail(host, *c.Commit.Author.Email) == login
+}
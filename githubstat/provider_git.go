@@ -0,0 +1,389 @@
+package githubstat
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+var (
+	// subject of merge commits created by GitHub, e.g. "Merge pull request #123 from bruceauyeung/fix-typo"
+	mergeCommitRegexp = regexp.MustCompile(`^Merge pull request #(\d+) from ([^/\s]+)/`)
+	// subject of squash commits created by GitHub, e.g. "fix typo (#123)"
+	squashCommitRegexp = regexp.MustCompile(`\(#(\d+)\)\s*$`)
+)
+
+// gitCommit is a commit parsed from git log.
+type gitCommit struct {
+	SHA         string
+	Parents     []string
+	AuthorName  string
+	AuthorEmail string
+	AuthorDate  time.Time
+	CommitDate  time.Time
+	Subject     string
+}
+
+func (c *gitCommit) toRepositoryCommit() *github.RepositoryCommit {
+	sha := c.SHA
+	message := c.Subject
+	name := c.AuthorName
+	email := c.AuthorEmail
+	date := c.AuthorDate
+	return &github.RepositoryCommit{
+		SHA: &sha,
+		Commit: &github.Commit{
+			SHA:     &sha,
+			Message: &message,
+			Author:  &github.CommitAuthor{Name: &name, Email: &email, Date: &date},
+		},
+	}
+}
+
+// gitPullRequest is a merged PR derived from a merge commit or a squash commit on the default branch.
+type gitPullRequest struct {
+	pr      *github.PullRequest
+	commits []*gitCommit // commits of the PR, the squash commit itself for squashed PRs
+	files   []*github.CommitFile
+}
+
+// gitProvider analyzes history of local clones without any API call. merged PRs are derived from
+// first-parent history of the default branch, so open PRs, labels and LGTM events are not available.
+// commits are attributed to users by author emails, see User.Emails.
+type gitProvider struct {
+	host string
+	// merged PRs by "owner/repo"
+	merged map[string][]*gitPullRequest
+	period statPeriod // period which PRs are derived in, the configured one if it is zero
+}
+
+func newGitProvider(host string, period statPeriod) *gitProvider {
+	return &gitProvider{host: host, merged: make(map[string][]*gitPullRequest), period: period}
+}
+
+// ownerDir returns directory of clones of an owner, i.e. "ownername" under clone dir,
+// or "hostname/ownername" for repos not on github.com.
+func (p *gitProvider) ownerDir(owner string) string {
+	if p.host == "" || p.host == DefaultHost {
+		return filepath.Join(Config.CloneDir, owner)
+	}
+	return filepath.Join(Config.CloneDir, p.host, owner)
+}
+
+// clonePath returns path of the local clone of a repository, which is configured in clones,
+// or "reponame" under owner dir.
+func (p *gitProvider) clonePath(owner string, repo string) string {
+	r := RepoParameters{OwnerName: &owner, RepoName: &repo, Host: p.host}
+	if p.host == DefaultHost {
+		r.Host = ""
+	}
+	if path, found := Config.Clones[r.String()]; found {
+		return path
+	}
+	return filepath.Join(p.ownerDir(owner), repo)
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s in %s: %v", strings.Join(args, " "), dir, err)
+	}
+	return string(out), nil
+}
+
+// defaultBranch returns the branch which origin/HEAD points to, or HEAD if there is no such remote branch.
+func defaultBranch(dir string) string {
+	if out, err := runGit(dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimSpace(out)
+	}
+	return "HEAD"
+}
+
+// gitLog parses "git log" of revisions with the given arguments.
+func gitLog(dir string, args ...string) ([]*gitCommit, error) {
+	args = append([]string{"log", "--format=%H%x00%P%x00%an%x00%ae%x00%aI%x00%cI%x00%s"}, args...)
+	out, err := runGit(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []*gitCommit
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 7 {
+			continue
+		}
+		authorDate, err := time.Parse(time.RFC3339, fields[4])
+		if err != nil {
+			return nil, err
+		}
+		commitDate, err := time.Parse(time.RFC3339, fields[5])
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, &gitCommit{
+			SHA:         fields[0],
+			Parents:     strings.Fields(fields[1]),
+			AuthorName:  fields[2],
+			AuthorEmail: fields[3],
+			AuthorDate:  authorDate,
+			CommitDate:  commitDate,
+			Subject:     fields[6],
+		})
+	}
+	return commits, nil
+}
+
+// gitDiffFiles lists files changed by a commit against its first parent.
+func gitDiffFiles(dir string, sha string) ([]*github.CommitFile, error) {
+	out, err := runGit(dir, "diff", "--numstat", sha+"^1", sha)
+	if err != nil {
+		return nil, err
+	}
+	var files []*github.CommitFile
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		// binary files are shown as "-"
+		additions, _ := strconv.Atoi(fields[0])
+		deletions, _ := strconv.Atoi(fields[1])
+		filename := fields[2]
+		changes := additions + deletions
+		files = append(files, &github.CommitFile{
+			Filename:  &filename,
+			Additions: &additions,
+			Deletions: &deletions,
+			Changes:   &changes,
+		})
+	}
+	return files, nil
+}
+
+// authorOf returns login of the configured user who authored the earliest commit among commits,
+// empty if none of them is authored by a configured user.
+func (p *gitProvider) authorOf(commits []*gitCommit) string {
+	// git log lists the latest commit first
+	for i := len(commits) - 1; i >= 0; i-- {
+		if login := loginByEmail(p.host, commits[i].AuthorEmail); login != commits[i].AuthorEmail {
+			return login
+		}
+	}
+	return ""
+}
+
+// listMergedPullRequests derives PRs merged in the period from first-parent history of the default branch.
+func (p *gitProvider) listMergedPullRequests(owner string, repo string) ([]*gitPullRequest, error) {
+	key := owner + "/" + repo
+	if prs, found := p.merged[key]; found {
+		return prs, nil
+	}
+	dir := p.clonePath(owner, repo)
+	period := p.period.orConfigured()
+	args := []string{"--first-parent", "--since=" + period.begin.Format(time.RFC3339)}
+	if !period.end.IsZero() {
+		args = append(args, "--until="+period.end.Format(time.RFC3339))
+	}
+	commits, err := gitLog(dir, append(args, defaultBranch(dir))...)
+	if err != nil {
+		return nil, err
+	}
+
+	var prs []*gitPullRequest
+	for _, c := range commits {
+		var number int
+		var login string
+		prCommits := []*gitCommit{c}
+		if m := mergeCommitRegexp.FindStringSubmatch(c.Subject); m != nil && len(c.Parents) == 2 {
+			number, _ = strconv.Atoi(m[1])
+			// commits of the merged branch
+			if prCommits, err = gitLog(dir, c.Parents[0]+".."+c.Parents[1]); err != nil {
+				return nil, err
+			}
+			// the owner in the subject is the owner of the head repo, which is the org itself for branches
+			// in the same repo, so it is only used when no commit of the branch is authored by a configured user
+			if login = p.authorOf(prCommits); login == "" {
+				login = m[2]
+			}
+		} else if m := squashCommitRegexp.FindStringSubmatch(c.Subject); m != nil {
+			number, _ = strconv.Atoi(m[1])
+			login = loginByEmail(p.host, c.AuthorEmail)
+		} else {
+			continue
+		}
+
+		mergedAt := c.CommitDate
+		if !period.contains(&mergedAt) {
+			continue
+		}
+		// the earliest authored commit is regarded as the time PR was created
+		createdAt := mergedAt
+		for _, pc := range prCommits {
+			if pc.AuthorDate.Before(createdAt) {
+				createdAt = pc.AuthorDate
+			}
+		}
+		files, err := gitDiffFiles(dir, c.SHA)
+		if err != nil {
+			return nil, err
+		}
+		var additions, deletions int
+		for _, f := range files {
+			additions += *f.Additions
+			deletions += *f.Deletions
+		}
+		title := c.Subject
+		state := "closed"
+		commitsNumber := len(prCommits)
+		changedFiles := len(files)
+		prs = append(prs, &gitPullRequest{
+			pr: &github.PullRequest{
+				Number:       &number,
+				Title:        &title,
+				State:        &state,
+				User:         &github.User{Login: &login},
+				CreatedAt:    &createdAt,
+				UpdatedAt:    &mergedAt,
+				ClosedAt:     &mergedAt,
+				MergedAt:     &mergedAt,
+				Commits:      &commitsNumber,
+				Additions:    &additions,
+				Deletions:    &deletions,
+				ChangedFiles: &changedFiles,
+			},
+			commits: prCommits,
+			files:   files,
+		})
+	}
+	p.merged[key] = prs
+	return prs, nil
+}
+
+func (p *gitProvider) findPullRequest(owner string, repo string, number int) (*gitPullRequest, error) {
+	prs, err := p.listMergedPullRequests(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range prs {
+		if *pr.pr.Number == number {
+			return pr, nil
+		}
+	}
+	return nil, fmt.Errorf("pull request %s not found in history of %s", pullRequestKey(owner, repo, number), p.clonePath(owner, repo))
+}
+
+// ListRepositories lists local clones under owner dir.
+func (p *gitProvider) ListRepositories(owner string) ([]*github.Repository, error) {
+	entries, err := ioutil.ReadDir(p.ownerDir(owner))
+	if err != nil {
+		return nil, err
+	}
+	var allRepos []*github.Repository
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(p.ownerDir(owner), e.Name(), ".git")); err != nil {
+			continue
+		}
+		login := owner
+		name := e.Name()
+		allRepos = append(allRepos, &github.Repository{Owner: &github.User{Login: &login}, Name: &name})
+	}
+	return allRepos, nil
+}
+
+// ListOpenPullRequests returns nothing because open PRs are not in git history.
+func (p *gitProvider) ListOpenPullRequests(owner string, repo string) ([]*github.PullRequest, error) {
+	return nil, nil
+}
+
+func (p *gitProvider) ListClosedPullRequests(owner string, repo string) ([]*github.PullRequest, error) {
+	prs, err := p.listMergedPullRequests(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var allPRs []*github.PullRequest
+	for _, pr := range prs {
+		allPRs = append(allPRs, pr.pr)
+	}
+	return allPRs, nil
+}
+
+// ListUpdatedPullRequests lists merged PRs updated since the given time, a PR derived from git history is
+// regarded as being updated when it was merged.
+func (p *gitProvider) ListUpdatedPullRequests(owner string, repo string, since time.Time) ([]*github.PullRequest, error) {
+	prs, err := p.ListClosedPullRequests(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var allPRs []*github.PullRequest
+	for _, pr := range prs {
+		if !pr.UpdatedAt.Before(since) {
+			allPRs = append(allPRs, pr)
+		}
+	}
+	return allPRs, nil
+}
+
+func (p *gitProvider) GetPullRequest(owner string, repo string, number int) (*github.PullRequest, error) {
+	pr, err := p.findPullRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	return pr.pr, nil
+}
+
+// GetLabelNames returns nothing because labels are not in git history.
+func (p *gitProvider) GetLabelNames(owner string, repo string, number int) ([]string, error) {
+	return nil, nil
+}
+
+func (p *gitProvider) GetLatestLGTMEvent(owner string, repo string, number int) (*github.IssueEvent, error) {
+	return nil, fmt.Errorf("no LGTM event found")
+}
+
+func (p *gitProvider) ListFiles(owner string, repo string, number int) ([]*github.CommitFile, error) {
+	pr, err := p.findPullRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	return pr.files, nil
+}
+
+func (p *gitProvider) GetClosedBy(owner string, repo string, number int) (string, error) {
+	return "", nil
+}
+
+// ListMergedCommits lists commits of merged PRs authored by the user whose login is author,
+// every commit is regarded as being merged when its PR was merged.
+func (p *gitProvider) ListMergedCommits(owner string, repo string, author string) ([]*PullRequestCommit, error) {
+	prs, err := p.listMergedPullRequests(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var prCommits []*PullRequestCommit
+	for _, pr := range prs {
+		var commits []*github.RepositoryCommit
+		for _, c := range pr.commits {
+			if loginByEmail(p.host, c.AuthorEmail) == author {
+				commits = append(commits, c.toRepositoryCommit())
+			}
+		}
+		for _, c := range filterCommits(commits) {
+			prCommits = append(prCommits, &PullRequestCommit{RepositoryCommit: c, Owner: owner, Repo: repo, MergedAt: pr.pr.MergedAt,
+				PullRequest: *pr.pr.Number, Resolution: CommitResolutionGitHistory})
+		}
+	}
+	return prCommits, nil
+}
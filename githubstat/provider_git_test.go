@@ -0,0 +1,110 @@
+package githubstat
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func Test_gitProviderListClosedPullRequests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "githubstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	repoDir := filepath.Join(dir, "kubernetes", "kubernetes")
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	git := func(date string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date,
+			"GIT_AUTHOR_NAME=bruce", "GIT_AUTHOR_EMAIL=bruce@example.com",
+			"GIT_COMMITTER_NAME=bot", "GIT_COMMITTER_EMAIL=bot@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name string, content string) {
+		if err := ioutil.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("2016-09-01T00:00:00Z", "init", "-q", "-b", "master")
+	write("README.md", "readme\n")
+	git("2016-09-01T00:00:00Z", "add", "-A")
+	git("2016-09-01T00:00:00Z", "commit", "-q", "-m", "initial commit")
+	// a PR merged by merge commit
+	git("2016-10-02T00:00:00Z", "checkout", "-q", "-b", "fix-typo")
+	write("a.go", "package a\n")
+	git("2016-10-02T00:00:00Z", "add", "-A")
+	git("2016-10-02T00:00:00Z", "commit", "-q", "-m", "add a.go")
+	write("a.go", "package a\n\nconst A = 1\n")
+	git("2016-10-03T00:00:00Z", "commit", "-q", "-am", "add const A")
+	git("2016-10-04T00:00:00Z", "checkout", "-q", "master")
+	git("2016-10-04T00:00:00Z", "merge", "-q", "--no-ff", "-m", "Merge pull request #1 from bruceauyeung/fix-typo", "fix-typo")
+	// a squashed PR
+	write("b.go", "package b\n")
+	git("2016-10-05T00:00:00Z", "add", "-A")
+	git("2016-10-05T00:00:00Z", "commit", "-q", "-m", "add b.go (#2)")
+	// a PR merged from a branch in the same repo, whose subject names the org instead of the author
+	git("2016-10-06T00:00:00Z", "checkout", "-q", "-b", "add-c")
+	write("c.go", "package c\n")
+	git("2016-10-06T00:00:00Z", "add", "-A")
+	git("2016-10-06T00:00:00Z", "commit", "-q", "-m", "add c.go")
+	git("2016-10-06T00:00:00Z", "checkout", "-q", "master")
+	git("2016-10-06T00:00:00Z", "merge", "-q", "--no-ff", "-m", "Merge pull request #3 from kubernetes/add-c", "add-c")
+	// a PR merged from a fork of somebody who is not configured
+	git("2016-10-07T00:00:00Z", "checkout", "-q", "-b", "add-d")
+	write("d.go", "package d\n")
+	git("2016-10-07T00:00:00Z", "add", "-A")
+	git("2016-10-07T00:00:00Z", "commit", "-q", "--author", "someone <someone@example.com>", "-m", "add d.go")
+	git("2016-10-07T00:00:00Z", "checkout", "-q", "master")
+	git("2016-10-07T00:00:00Z", "merge", "-q", "--no-ff", "-m", "Merge pull request #4 from someone/add-d", "add-d")
+	// not a PR
+	write("README.md", "readme\nmore\n")
+	git("2016-10-08T00:00:00Z", "commit", "-q", "-am", "update readme")
+
+	cloneDir := Config.CloneDir
+	users := Config.Users
+	defer func() {
+		Config.CloneDir = cloneDir
+		Config.Users = users
+	}()
+	Config.CloneDir = dir
+	Config.Users = []User{{Name: "bruceauyeung", Emails: []string{"bruce@example.com"}}}
+
+	p := newGitProvider("", statPeriod{})
+	prs, err := p.ListClosedPullRequests("kubernetes", "kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 4 {
+		t.Fatalf("got %d PRs, want 4", len(prs))
+	}
+	// git log lists the latest commit first
+	forked, sameRepo, squashed, merged := prs[0], prs[1], prs[2], prs[3]
+	if *sameRepo.Number != 3 || *sameRepo.User.Login != "bruceauyeung" {
+		t.Errorf("PR from a branch in the same repo is credited to %s, want bruceauyeung", *sameRepo.User.Login)
+	}
+	if *forked.Number != 4 || *forked.User.Login != "someone" {
+		t.Errorf("PR from a fork of an unknown author is credited to %s, want someone", *forked.User.Login)
+	}
+	if *merged.Number != 1 || *merged.User.Login != "bruceauyeung" || *merged.Commits != 2 ||
+		*merged.Additions != 3 || *merged.ChangedFiles != 1 || merged.CreatedAt.Day() != 2 || merged.MergedAt.Day() != 4 {
+		t.Errorf("unexpected merged PR: %+v", merged)
+	}
+	if *squashed.Number != 2 || *squashed.User.Login != "bruceauyeung" || *squashed.Commits != 1 || *squashed.Additions != 1 {
+		t.Errorf("unexpected squashed PR: %+v", squashed)
+	}
+	commits, err := p.ListMergedCommits("kubernetes", "kubernetes", "bruceauyeung")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 4 {
+		t.Errorf("got %d merged commits, want 4", len(commits))
+	}
+}
@@ -0,0 +1,343 @@
+package githubstat
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// Gitea API is modeled after GitHub's, so most of its responses are decoded into go-github types directly.
+type giteaPullRequest struct {
+	github.PullRequest
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+type giteaTimelineEvent struct {
+	Type      string       `json:"type"` // "label", "close", "reopen", ...
+	Body      string       `json:"body"` // "1" if label was added, empty if it was removed
+	User      *github.User `json:"user"`
+	CreatedAt time.Time    `json:"created_at"`
+	Label     *struct {
+		Name string `json:"name"`
+	} `json:"label"`
+}
+
+type giteaReview struct {
+	User        *github.User `json:"user"`
+	State       string       `json:"state"` // APPROVED, REQUEST_CHANGES, COMMENT, PENDING
+	SubmittedAt time.Time    `json:"submitted_at"`
+}
+
+// giteaProvider fetches pull requests by Gitea API (v1), it also works with Forgejo.
+// approved reviews are regarded as a LGTM label, so that approved PRs are counted as LGTM'ed PRs.
+type giteaProvider struct {
+	httpClient *http.Client
+	baseURL    string
+	// PRs fetched by listing, keyed by "owner/repo#number"
+	cache map[string]*giteaPullRequest
+	// closed PRs by "owner/repo" and commits of merged PRs by "owner/repo#number", which are listed once
+	// for all users
+	closed  map[string][]*github.PullRequest
+	commits map[string][]*github.RepositoryCommit
+	host    string     // name of the host, by which commit authors are mapped to users
+	period  statPeriod // period which PRs are listed in, the configured one if it is zero
+}
+
+func newGiteaProvider(proxyClient *ProxyClient, period statPeriod) *giteaProvider {
+	return &giteaProvider{
+		httpClient: proxyClient.getHTTPClient(),
+		baseURL:    strings.TrimSuffix(proxyClient.getHost().BaseURL, "/") + "/",
+		cache:      make(map[string]*giteaPullRequest),
+		closed:     make(map[string][]*github.PullRequest),
+		commits:    make(map[string][]*github.RepositoryCommit),
+		host:       proxyClient.getHost().Name,
+		period:     period,
+	}
+}
+
+func giteaRepoPath(owner string, repo string) string {
+	return "repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+// list gets all pages of path, appendPage decodes a page and appends its items, and tells whether listing should stop.
+func (p *giteaProvider) list(path string, query url.Values, newPage func() interface{}, appendPage func(interface{}) bool) error {
+	query.Set("limit", "50")
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		v := newPage()
+		resp, err := getJSON(p.httpClient, p.baseURL+path+"?"+query.Encode(), v)
+		if err != nil {
+			return err
+		}
+		if appendPage(v) || !strings.Contains(resp.Header.Get("Link"), `rel="next"`) {
+			return nil
+		}
+	}
+}
+
+func (p *giteaProvider) listPullRequests(owner string, repo string, query url.Values,
+	filter func(*github.PullRequest) (bool, bool)) ([]*github.PullRequest, error) {
+	var allPRs []*github.PullRequest
+	page := 1
+	err := p.list(giteaRepoPath(owner, repo)+"/pulls", query,
+		func() interface{} { return &[]*giteaPullRequest{} },
+		func(v interface{}) bool {
+			fmt.Printf("page:%d fin\n", page)
+			page++
+			for _, pr := range *v.(*[]*giteaPullRequest) {
+				keep, stop := filter(&pr.PullRequest)
+				if stop {
+					return true
+				}
+				if keep {
+					p.cache[pullRequestKey(owner, repo, *pr.Number)] = pr
+					allPRs = append(allPRs, &pr.PullRequest)
+				}
+			}
+			return false
+		})
+	return allPRs, err
+}
+
+func (p *giteaProvider) getPullRequest(owner string, repo string, number int) (*giteaPullRequest, error) {
+	key := pullRequestKey(owner, repo, number)
+	if pr, found := p.cache[key]; found {
+		return pr, nil
+	}
+	pr := &giteaPullRequest{}
+	if _, err := getJSON(p.httpClient, fmt.Sprintf("%s%s/pulls/%d", p.baseURL, giteaRepoPath(owner, repo), number), pr); err != nil {
+		return nil, err
+	}
+	p.cache[key] = pr
+	return pr, nil
+}
+
+func (p *giteaProvider) listPullRequestCommits(owner string, repo string, number int) ([]*github.RepositoryCommit, error) {
+	key := pullRequestKey(owner, repo, number)
+	if commits, found := p.commits[key]; found {
+		return commits, nil
+	}
+	var allCommits []*github.RepositoryCommit
+	err := p.list(fmt.Sprintf("%s/pulls/%d/commits", giteaRepoPath(owner, repo), number), url.Values{},
+		func() interface{} { return &[]*github.RepositoryCommit{} },
+		func(v interface{}) bool {
+			allCommits = append(allCommits, *v.(*[]*github.RepositoryCommit)...)
+			return false
+		})
+	if err != nil {
+		return nil, err
+	}
+	p.commits[key] = allCommits
+	return allCommits, nil
+}
+
+func (p *giteaProvider) listTimeline(owner string, repo string, number int) ([]*giteaTimelineEvent, error) {
+	var allEvents []*giteaTimelineEvent
+	err := p.list(fmt.Sprintf("%s/issues/%d/timeline", giteaRepoPath(owner, repo), number), url.Values{},
+		func() interface{} { return &[]*giteaTimelineEvent{} },
+		func(v interface{}) bool {
+			allEvents = append(allEvents, *v.(*[]*giteaTimelineEvent)...)
+			return false
+		})
+	return allEvents, err
+}
+
+func (p *giteaProvider) listReviews(owner string, repo string, number int) ([]*giteaReview, error) {
+	var allReviews []*giteaReview
+	err := p.list(fmt.Sprintf("%s/pulls/%d/reviews", giteaRepoPath(owner, repo), number), url.Values{},
+		func() interface{} { return &[]*giteaReview{} },
+		func(v interface{}) bool {
+			allReviews = append(allReviews, *v.(*[]*giteaReview)...)
+			return false
+		})
+	return allReviews, err
+}
+
+// firstApproval returns the first approved review, or nil if the PR was not approved.
+func (p *giteaProvider) firstApproval(owner string, repo string, number int) (*giteaReview, error) {
+	reviews, err := p.listReviews(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var first *giteaReview
+	for _, r := range reviews {
+		if r.State == "APPROVED" && (first == nil || r.SubmittedAt.Before(first.SubmittedAt)) {
+			first = r
+		}
+	}
+	return first, nil
+}
+
+func (p *giteaProvider) ListRepositories(owner string) ([]*github.Repository, error) {
+	var allRepos []*github.Repository
+	newPage := func() interface{} { return &[]*github.Repository{} }
+	appendPage := func(v interface{}) bool {
+		allRepos = append(allRepos, *v.(*[]*github.Repository)...)
+		return false
+	}
+	// owner is either an organization or a user, repos of the user are listed only if the organization is not found
+	orgErr := p.list("orgs/"+url.PathEscape(owner)+"/repos", url.Values{}, newPage, appendPage)
+	if orgErr == nil {
+		return allRepos, nil
+	}
+	if !isNotFound(orgErr) {
+		return nil, orgErr
+	}
+	allRepos = nil
+	if err := p.list("users/"+url.PathEscape(owner)+"/repos", url.Values{}, newPage, appendPage); err != nil {
+		return nil, fmt.Errorf("%s is neither an organization nor a user: %v, %v", owner, orgErr, err)
+	}
+	return allRepos, nil
+}
+
+// ListOpenPullRequests lists open PRs, which Gitea sorts by create time descendingly by default.
+func (p *giteaProvider) ListOpenPullRequests(owner string, repo string) ([]*github.PullRequest, error) {
+	return p.listPullRequests(owner, repo, url.Values{"state": {"open"}}, p.period.orConfigured().filterOpen)
+}
+
+func (p *giteaProvider) ListClosedPullRequests(owner string, repo string) ([]*github.PullRequest, error) {
+	key := owner + "/" + repo
+	if prs, found := p.closed[key]; found {
+		return prs, nil
+	}
+	prs, err := p.listPullRequests(owner, repo, url.Values{"state": {"closed"}, "sort": {"recentupdate"}}, p.period.orConfigured().filterClosed)
+	if err != nil {
+		return nil, err
+	}
+	p.closed[key] = prs
+	return prs, nil
+}
+
+func (p *giteaProvider) ListUpdatedPullRequests(owner string, repo string, since time.Time) ([]*github.PullRequest, error) {
+	return p.listPullRequests(owner, repo, url.Values{"state": {"all"}, "sort": {"recentupdate"}}, filterUpdatedPullRequest(since))
+}
+
+// GetPullRequest gets a PR with commits number, which Gitea does not return with the PR.
+func (p *giteaProvider) GetPullRequest(owner string, repo string, number int) (*github.PullRequest, error) {
+	pr, err := p.getPullRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Commits == nil {
+		commits, err := p.listPullRequestCommits(owner, repo, number)
+		if err != nil {
+			return nil, err
+		}
+		commitsNumber := len(commits)
+		pr.Commits = &commitsNumber
+	}
+	return &pr.PullRequest, nil
+}
+
+// GetLabelNames gets labels of a PR, with a LGTM label if the PR was approved.
+func (p *giteaProvider) GetLabelNames(owner string, repo string, number int) ([]string, error) {
+	pr, err := p.getPullRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var labelNames []string
+	for _, l := range pr.Labels {
+		labelNames = append(labelNames, l.Name)
+	}
+	if !hasLGTMLabel(labelNames) {
+		approval, err := p.firstApproval(owner, repo, number)
+		if err != nil {
+			return nil, err
+		}
+		if approval != nil {
+			labelNames = append(labelNames, LGTMLabels[0])
+		}
+	}
+	return labelNames, nil
+}
+
+// GetLatestLGTMEvent gets the first event that a LGTM label was added, or the first approval of a PR.
+func (p *giteaProvider) GetLatestLGTMEvent(owner string, repo string, number int) (*github.IssueEvent, error) {
+	events, err := p.listTimeline(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		if e.Type == "label" && e.Body == "1" && e.Label != nil && isLGTMLabel(e.Label.Name) {
+			labeled := "labeled"
+			name := e.Label.Name
+			createdAt := e.CreatedAt
+			return &github.IssueEvent{Event: &labeled, CreatedAt: &createdAt, Label: &github.Label{Name: &name}}, nil
+		}
+	}
+	approval, err := p.firstApproval(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	if approval != nil {
+		approved := "approved"
+		createdAt := approval.SubmittedAt
+		return &github.IssueEvent{Event: &approved, CreatedAt: &createdAt}, nil
+	}
+	return nil, fmt.Errorf("no LGTM event found")
+}
+
+func (p *giteaProvider) ListFiles(owner string, repo string, number int) ([]*github.CommitFile, error) {
+	var allFiles []*github.CommitFile
+	err := p.list(fmt.Sprintf("%s/pulls/%d/files", giteaRepoPath(owner, repo), number), url.Values{},
+		func() interface{} { return &[]*github.CommitFile{} },
+		func(v interface{}) bool {
+			allFiles = append(allFiles, *v.(*[]*github.CommitFile)...)
+			return false
+		})
+	return allFiles, err
+}
+
+// GetClosedBy gets user of the last close event of a PR.
+func (p *giteaProvider) GetClosedBy(owner string, repo string, number int) (string, error) {
+	events, err := p.listTimeline(owner, repo, number)
+	if err != nil {
+		return "", err
+	}
+	var closedBy string
+	for _, e := range events {
+		if e.Type == "close" && e.User != nil && e.User.Login != nil {
+			closedBy = *e.User.Login
+		}
+	}
+	return closedBy, nil
+}
+
+// ListMergedCommits lists commits authored by author in PRs of anyone merged in the period, commit authors are
+// accounts which Gitea links commits to, or users of their emails. every commit is regarded as being merged
+// when its PR was merged.
+func (p *giteaProvider) ListMergedCommits(owner string, repo string, author string) ([]*PullRequestCommit, error) {
+	prs, err := p.ListClosedPullRequests(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var prCommits []*PullRequestCommit
+	// commits of author may be in PRs of anyone
+	for _, pr := range prs {
+		if pr.MergedAt == nil || !p.period.orConfigured().contains(pr.MergedAt) {
+			continue
+		}
+		commits, err := p.listPullRequestCommits(owner, repo, *pr.Number)
+		if err != nil {
+			return nil, err
+		}
+		var authored []*github.RepositoryCommit
+		for _, c := range commits {
+			if authoredBy(p.host, c, author) {
+				authored = append(authored, c)
+			}
+		}
+		for _, c := range filterCommits(authored) {
+			prCommits = append(prCommits, &PullRequestCommit{RepositoryCommit: c, Owner: owner, Repo: repo, MergedAt: pr.MergedAt,
+				PullRequest: *pr.Number, Resolution: CommitResolutionPullRequest})
+		}
+	}
+	return prCommits, nil
+}
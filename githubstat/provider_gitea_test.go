@@ -0,0 +1,219 @@
+package githubstat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func stringOf(s string) *string {
+	return &s
+}
+
+func giteaUser(login string) *github.User {
+	return &github.User{Login: &login}
+}
+
+// newPullRequestFakeGitea serves PRs of bruceauyeung and tanshanshan, the stat period is from 2016-10-01
+// to 2016-12-30.
+func newPullRequestFakeGitea(t *testing.T) *fakeGitea {
+	f := newFakeGitea(t)
+	// open PRs
+	f.addPullRequest(10, "bruceauyeung", "open", "2016-12-26T00:00:00Z", "2016-12-26T00:00:00Z", "")
+	f.reviews[10] = []*giteaReview{
+		{User: giteaUser("someone"), State: "COMMENT", SubmittedAt: *fakeTime("2016-12-26T00:00:00Z")},
+		{User: giteaUser("bruceauyeung"), State: "APPROVED", SubmittedAt: *fakeTime("2016-12-28T00:00:00Z")},
+		{User: giteaUser("tanshanshan"), State: "APPROVED", SubmittedAt: *fakeTime("2016-12-27T00:00:00Z")},
+	}
+	f.addPullRequest(9, "tanshanshan", "open", "2016-11-01T00:00:00Z", "2016-11-01T00:00:00Z", "").Labels =
+		[]struct {
+			Name string `json:"name"`
+		}{{"lgtm"}}
+	// a removed label is not a LGTM event
+	f.timeline[9] = []*giteaTimelineEvent{
+		{Type: "label", Body: "", CreatedAt: *fakeTime("2016-11-02T00:00:00Z"), Label: &struct {
+			Name string `json:"name"`
+		}{"lgtm"}},
+		{Type: "comment", CreatedAt: *fakeTime("2016-11-02T12:00:00Z")},
+		{Type: "label", Body: "1", CreatedAt: *fakeTime("2016-11-03T00:00:00Z"), Label: &struct {
+			Name string `json:"name"`
+		}{"lgtm"}},
+	}
+	f.addPullRequest(8, "bruceauyeung", "open", "2016-09-15T00:00:00Z", "2016-09-15T00:00:00Z", "")
+	// closed PRs, PR 6 was created before stat period but merged in it
+	f.addPullRequest(6, "bruceauyeung", "closed", "2016-09-01T00:00:00Z", "2016-12-28T00:00:00Z", "2016-12-28T00:00:00Z")
+	f.addPullRequest(5, "bruceauyeung", "closed", "2016-12-20T00:00:00Z", "2016-12-25T00:00:00Z", "2016-12-25T00:00:00Z")
+	f.addPullRequest(4, "bruceauyeung", "closed", "2016-09-20T00:00:00Z", "2016-10-10T00:00:00Z", "2016-10-10T00:00:00Z")
+	f.addPullRequest(3, "tanshanshan", "closed", "2016-10-05T00:00:00Z", "2016-11-05T00:00:00Z", "")
+	f.timeline[3] = []*giteaTimelineEvent{
+		{Type: "close", User: giteaUser("tanshanshan"), CreatedAt: *fakeTime("2016-11-01T00:00:00Z")},
+		{Type: "reopen", User: giteaUser("tanshanshan"), CreatedAt: *fakeTime("2016-11-02T00:00:00Z")},
+		{Type: "close", User: giteaUser("bruceauyeung"), CreatedAt: *fakeTime("2016-11-05T00:00:00Z")},
+	}
+	f.addPullRequest(2, "bruceauyeung", "closed", "2016-08-01T00:00:00Z", "2016-09-10T00:00:00Z", "2016-09-10T00:00:00Z")
+	f.addPullRequest(1, "bruceauyeung", "closed", "2016-07-01T00:00:00Z", "2016-08-01T00:00:00Z", "2016-08-01T00:00:00Z")
+	// commits, which are linked to accounts of their authors unless login is empty
+	commit := func(sha string, login string, email string, message string) *github.RepositoryCommit {
+		c := &github.RepositoryCommit{SHA: stringOf(sha), Commit: &github.Commit{Message: stringOf(message),
+			Author: &github.CommitAuthor{Email: stringOf(email)}}}
+		if login != "" {
+			c.Author = giteaUser(login)
+		}
+		return c
+	}
+	f.commits[6] = []*github.RepositoryCommit{commit("aaa", "bruceauyeung", "", "fix typo")}
+	// tanshanshan's commit is in PR 5 of bruceauyeung
+	f.commits[5] = []*github.RepositoryCommit{commit("bbb", "bruceauyeung", "", "add docs"),
+		commit("ccc", "bruceauyeung", "", "Merge branch 'master' into add-docs"), commit("fff", "tanshanshan", "", "fix docs")}
+	f.commits[4] = []*github.RepositoryCommit{commit("ddd", "", "bruce@example.com", "add tests")}
+	f.commits[2] = []*github.RepositoryCommit{commit("eee", "bruceauyeung", "", "before stat period")}
+	return f
+}
+
+// listRequests returns requests listing PRs among requests.
+func listRequests(requests []string) []string {
+	var lists []string
+	for _, r := range requests {
+		if strings.Contains(r, "/pulls?") {
+			lists = append(lists, r)
+		}
+	}
+	return lists
+}
+
+func Test_giteaProvider(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	users := Config.Users
+	defer func() { Config.Users = users }()
+	Config.Users = []User{{Name: "bruceauyeung", Emails: []string{"bruce@example.com"}}, {Name: "tanshanshan"}}
+	f := newPullRequestFakeGitea(t)
+	defer f.Close()
+	p := f.provider()
+
+	// PR 8 created before stat period stops listing on the second page
+	open, err := p.ListOpenPullRequests("kubernetes", "kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numbers := pullRequestNumbers(open); !equalInts(numbers, []int{10, 9}) {
+		t.Errorf("open PRs are %v, want [10 9]", numbers)
+	}
+	if lists := listRequests(f.requests); len(lists) != 2 || !strings.Contains(lists[1], "page=2") {
+		t.Errorf("requests of open PRs are %v", lists)
+	}
+
+	// closed PRs are listed by update time, PR 2 updated before stat period stops listing on the third page
+	closed, err := p.ListClosedPullRequests("kubernetes", "kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numbers := pullRequestNumbers(closed); !equalInts(numbers, []int{6, 5, 3, 4}) {
+		t.Errorf("closed PRs are %v, want [6 5 3 4]", numbers)
+	}
+	lists := len(listRequests(f.requests))
+	if lists != 5 {
+		t.Errorf("%d requests of open and closed PRs, want 5", lists)
+	}
+	// closed PRs are listed once for all users
+	if _, err := p.ListClosedPullRequests("kubernetes", "kubernetes"); err != nil {
+		t.Fatal(err)
+	}
+	commits, err := p.ListMergedCommits("kubernetes", "kubernetes", "bruceauyeung")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listRequests(f.requests)) != lists {
+		t.Errorf("closed PRs are listed again: %v", listRequests(f.requests)[lists:])
+	}
+	var shas []string
+	for _, c := range commits {
+		shas = append(shas, *c.RepositoryCommit.SHA)
+	}
+	// the commit not linked to any account is of the user of its email
+	if strings.Join(shas, " ") != "aaa bbb ddd" || commits[1].PullRequest != 5 || !commits[1].MergedAt.Equal(*fakeTime("2016-12-25T00:00:00Z")) {
+		t.Errorf("merged commits of bruceauyeung are %v", shas)
+	}
+	// commits are of their own authors instead of authors of PRs, and listed once for all users
+	requests := len(f.requests)
+	if commits, err := p.ListMergedCommits("kubernetes", "kubernetes", "tanshanshan"); err != nil || len(commits) != 1 ||
+		*commits[0].RepositoryCommit.SHA != "fff" || commits[0].PullRequest != 5 {
+		t.Errorf("merged commits of tanshanshan are %+v, %v, want fff of PR 5", commits, err)
+	}
+	if len(f.requests) != requests {
+		t.Errorf("commits are listed again: %v", f.requests[requests:])
+	}
+
+	updated, err := p.ListUpdatedPullRequests("kubernetes", "kubernetes", *fakeTime("2016-11-01T00:00:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numbers := pullRequestNumbers(updated); !equalInts(numbers, []int{6, 10, 5, 3, 9}) {
+		t.Errorf("PRs updated since 2016-11-01 are %v, want [6 10 5 3 9]", numbers)
+	}
+	if lists := listRequests(f.requests); !strings.Contains(lists[len(lists)-1], "sort=recentupdate") ||
+		!strings.Contains(lists[len(lists)-1], "state=all") {
+		t.Errorf("updated PRs are listed by %s", lists[len(lists)-1])
+	}
+
+	// Gitea returns no commits number with PRs
+	pr, err := p.GetPullRequest("kubernetes", "kubernetes", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *pr.Commits != 3 {
+		t.Errorf("PR 5 has %d commits, want 3", *pr.Commits)
+	}
+
+	// approved reviews are LGTM labels
+	if labels, err := p.GetLabelNames("kubernetes", "kubernetes", 10); err != nil || len(labels) != 1 || labels[0] != LGTMLabels[0] {
+		t.Errorf("labels of PR 10 are %v, %v, want [%s]", labels, err, LGTMLabels[0])
+	}
+	event, err := p.GetLatestLGTMEvent("kubernetes", "kubernetes", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *event.Event != "approved" || !event.CreatedAt.Equal(*fakeTime("2016-12-27T00:00:00Z")) {
+		t.Errorf("LGTM event of PR 10 is %s at %v, want approved at 2016-12-27", *event.Event, event.CreatedAt)
+	}
+	if labels, err := p.GetLabelNames("kubernetes", "kubernetes", 9); err != nil || len(labels) != 1 || labels[0] != "lgtm" {
+		t.Errorf("labels of PR 9 are %v, %v, want [lgtm]", labels, err)
+	}
+	event, err = p.GetLatestLGTMEvent("kubernetes", "kubernetes", 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *event.Label.Name != "lgtm" || !event.CreatedAt.Equal(*fakeTime("2016-11-03T00:00:00Z")) {
+		t.Errorf("LGTM event of PR 9 is %s at %v, want lgtm at 2016-11-03", *event.Label.Name, event.CreatedAt)
+	}
+	if closedBy, err := p.GetClosedBy("kubernetes", "kubernetes", 3); err != nil || closedBy != "bruceauyeung" {
+		t.Errorf("PR 3 is closed by %q, %v, want bruceauyeung", closedBy, err)
+	}
+}
+
+func Test_giteaProviderListRepositories(t *testing.T) {
+	f := newFakeGitea(t)
+	defer f.Close()
+	for _, name := range []string{"kubernetes", "website", "charts"} {
+		f.orgs["kubernetes"] = append(f.orgs["kubernetes"], &github.Repository{Owner: giteaUser("kubernetes"),
+			Name: stringOf(name)})
+	}
+	f.users["bruceauyeung"] = []*github.Repository{{Owner: giteaUser("bruceauyeung"), Name: stringOf("dotfiles")}}
+	p := f.provider()
+
+	// repos of organizations are paged
+	if repos, err := p.ListRepositories("kubernetes"); err != nil || len(repos) != 3 || *repos[2].Name != "charts" {
+		t.Errorf("repos of organization kubernetes are %+v, %v", repos, err)
+	}
+	// owners which are not organizations are users
+	if repos, err := p.ListRepositories("bruceauyeung"); err != nil || len(repos) != 1 || *repos[0].Name != "dotfiles" {
+		t.Errorf("repos of user bruceauyeung are %+v, %v", repos, err)
+	}
+	requests := len(f.requests)
+	if _, err := p.ListRepositories("broken"); err == nil || !strings.Contains(err.Error(), "500") || len(f.requests) != requests+1 {
+		t.Errorf("listing repos of a broken organization should fail with its error, got %v", err)
+	}
+	if _, err := p.ListRepositories("nobody"); err == nil || !strings.Contains(err.Error(), "neither an organization nor a user") {
+		t.Errorf("listing repos of nobody should fail, got %v", err)
+	}
+}
@@ -0,0 +1,527 @@
+package githubstat
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const gitlabApprovedNote = "approved this merge request"
+
+type gitlabUser struct {
+	Username string `json:"username"`
+}
+
+type gitlabMergeRequest struct {
+	IID       int         `json:"iid"`
+	Title     string      `json:"title"`
+	WebURL    string      `json:"web_url"`
+	State     string      `json:"state"` // opened, closed, locked or merged
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	MergedAt  *time.Time  `json:"merged_at"`
+	ClosedAt  *time.Time  `json:"closed_at"`
+	Author    *gitlabUser `json:"author"`
+	ClosedBy  *gitlabUser `json:"closed_by"`
+	Labels    []string    `json:"labels"`
+}
+
+type gitlabCommit struct {
+	ID          string    `json:"id"`
+	Message     string    `json:"message"`
+	AuthorName  string    `json:"author_name"`
+	AuthorEmail string    `json:"author_email"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type gitlabLabelEvent struct {
+	Action    string    `json:"action"` // add or remove
+	CreatedAt time.Time `json:"created_at"`
+	Label     *struct {
+		Name string `json:"name"`
+	} `json:"label"`
+}
+
+type gitlabDiff struct {
+	NewPath string `json:"new_path"`
+	Diff    string `json:"diff"`
+}
+
+type gitlabProject struct {
+	Path       string `json:"path"`
+	Visibility string `json:"visibility"`
+	Archived   bool   `json:"archived"`
+	Namespace  struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+	ForkedFromProject *struct{} `json:"forked_from_project"`
+	Topics            []string  `json:"topics"`
+}
+
+type gitlabNote struct {
+	Body      string      `json:"body"`
+	System    bool        `json:"system"`
+	Author    *gitlabUser `json:"author"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// toPullRequest maps a merge request onto the PR of GitHub, iid of merge request is used as PR number.
+func (mr *gitlabMergeRequest) toPullRequest() *github.PullRequest {
+	number := mr.IID
+	title := mr.Title
+	url := mr.WebURL
+	state := "open"
+	if mr.State != "opened" {
+		state = "closed"
+	}
+	createdAt := mr.CreatedAt
+	updatedAt := mr.UpdatedAt
+	pr := &github.PullRequest{
+		Number:    &number,
+		Title:     &title,
+		HTMLURL:   &url,
+		State:     &state,
+		CreatedAt: &createdAt,
+		UpdatedAt: &updatedAt,
+		ClosedAt:  mr.ClosedAt,
+		MergedAt:  mr.MergedAt,
+	}
+	if mr.State == "merged" && mr.ClosedAt == nil {
+		pr.ClosedAt = mr.MergedAt
+	}
+	if mr.Author != nil {
+		login := mr.Author.Username
+		pr.User = &github.User{Login: &login}
+	}
+	return pr
+}
+
+func (c *gitlabCommit) toRepositoryCommit() *github.RepositoryCommit {
+	sha := c.ID
+	message := c.Message
+	name := c.AuthorName
+	email := c.AuthorEmail
+	date := c.CreatedAt
+	return &github.RepositoryCommit{
+		SHA: &sha,
+		Commit: &github.Commit{
+			SHA:     &sha,
+			Message: &message,
+			Author:  &github.CommitAuthor{Name: &name, Email: &email, Date: &date},
+		},
+	}
+}
+
+// gitlabProvider fetches merge requests by GitLab REST API (v4). approvals of a merge request are
+// regarded as a LGTM label, so that approved merge requests are counted as LGTM'ed PRs.
+type gitlabProvider struct {
+	httpClient *http.Client
+	baseURL    string
+	host       string // name of the host, by which commit authors are mapped to users
+	// merge requests fetched by listing, keyed by "owner/repo#iid"
+	cache map[string]*gitlabMergeRequest
+	// changed files of merge requests, keyed by "owner/repo#iid"
+	files map[string][]*github.CommitFile
+	// merge requests merged in the period by "owner/repo", and their commits by "owner/repo#iid",
+	// which are listed once for all users
+	merged  map[string][]*gitlabMergeRequest
+	commits map[string][]*gitlabCommit
+	period  statPeriod // period which merge requests are listed in, the configured one if it is zero
+}
+
+func newGitLabProvider(proxyClient *ProxyClient, period statPeriod) *gitlabProvider {
+	return &gitlabProvider{
+		httpClient: proxyClient.getHTTPClient(),
+		baseURL:    strings.TrimSuffix(proxyClient.getHost().BaseURL, "/") + "/",
+		host:       proxyClient.getHost().Name,
+		cache:      make(map[string]*gitlabMergeRequest),
+		files:      make(map[string][]*github.CommitFile),
+		merged:     make(map[string][]*gitlabMergeRequest),
+		commits:    make(map[string][]*gitlabCommit),
+		period:     period,
+	}
+}
+
+func gitlabProjectPath(owner string, repo string) string {
+	return "projects/" + url.PathEscape(owner+"/"+repo)
+}
+
+// list gets all pages of path, appendPage decodes a page and appends its items, and tells whether listing should stop.
+func (p *gitlabProvider) list(path string, query url.Values, newPage func() interface{}, appendPage func(interface{}) bool) error {
+	query.Set("per_page", "100")
+	page := "1"
+	for page != "" {
+		query.Set("page", page)
+		v := newPage()
+		resp, err := getJSON(p.httpClient, p.baseURL+path+"?"+query.Encode(), v)
+		if err != nil {
+			return err
+		}
+		if appendPage(v) {
+			break
+		}
+		page = resp.Header.Get("X-Next-Page")
+	}
+	return nil
+}
+
+func (p *gitlabProvider) listMergeRequests(owner string, repo string, query url.Values,
+	filter func(*github.PullRequest) (bool, bool)) ([]*gitlabMergeRequest, error) {
+	var allMRs []*gitlabMergeRequest
+	page := 1
+	err := p.list(gitlabProjectPath(owner, repo)+"/merge_requests", query,
+		func() interface{} { return &[]*gitlabMergeRequest{} },
+		func(v interface{}) bool {
+			fmt.Printf("page:%d fin\n", page)
+			page++
+			for _, mr := range *v.(*[]*gitlabMergeRequest) {
+				keep, stop := filter(mr.toPullRequest())
+				if stop {
+					return true
+				}
+				if keep {
+					p.cache[pullRequestKey(owner, repo, mr.IID)] = mr
+					allMRs = append(allMRs, mr)
+				}
+			}
+			return false
+		})
+	return allMRs, err
+}
+
+func (p *gitlabProvider) getMergeRequest(owner string, repo string, number int) (*gitlabMergeRequest, error) {
+	key := pullRequestKey(owner, repo, number)
+	if mr, found := p.cache[key]; found {
+		return mr, nil
+	}
+	mr := &gitlabMergeRequest{}
+	if _, err := getJSON(p.httpClient, fmt.Sprintf("%s%s/merge_requests/%d", p.baseURL, gitlabProjectPath(owner, repo), number), mr); err != nil {
+		return nil, err
+	}
+	p.cache[key] = mr
+	return mr, nil
+}
+
+func (p *gitlabProvider) listMergeRequestCommits(owner string, repo string, number int) ([]*gitlabCommit, error) {
+	key := pullRequestKey(owner, repo, number)
+	if commits, found := p.commits[key]; found {
+		return commits, nil
+	}
+	var allCommits []*gitlabCommit
+	err := p.list(fmt.Sprintf("%s/merge_requests/%d/commits", gitlabProjectPath(owner, repo), number), url.Values{},
+		func() interface{} { return &[]*gitlabCommit{} },
+		func(v interface{}) bool {
+			allCommits = append(allCommits, *v.(*[]*gitlabCommit)...)
+			return false
+		})
+	if err != nil {
+		return nil, err
+	}
+	p.commits[key] = allCommits
+	return allCommits, nil
+}
+
+// listApprovalNotes lists system notes of approvals in chronological order.
+func (p *gitlabProvider) listApprovalNotes(owner string, repo string, number int) ([]*gitlabNote, error) {
+	var approvals []*gitlabNote
+	query := url.Values{"sort": {"asc"}, "order_by": {"created_at"}}
+	err := p.list(fmt.Sprintf("%s/merge_requests/%d/notes", gitlabProjectPath(owner, repo), number), query,
+		func() interface{} { return &[]*gitlabNote{} },
+		func(v interface{}) bool {
+			for _, note := range *v.(*[]*gitlabNote) {
+				if note.System && note.Body == gitlabApprovedNote {
+					approvals = append(approvals, note)
+				}
+			}
+			return false
+		})
+	return approvals, err
+}
+
+func (p *gitlabProvider) ListRepositories(owner string) ([]*github.Repository, error) {
+	var allRepos []*github.Repository
+	newPage := func() interface{} {
+		return &[]*gitlabProject{}
+	}
+	appendPage := func(v interface{}) bool {
+		for _, project := range *v.(*[]*gitlabProject) {
+			login := project.Namespace.FullPath
+			name := project.Path
+			fork := project.ForkedFromProject != nil
+			private := project.Visibility != "public"
+			archived := project.Archived
+			allRepos = append(allRepos, &github.Repository{
+				Owner:    &github.User{Login: &login},
+				Name:     &name,
+				Fork:     &fork,
+				Private:  &private,
+				Archived: &archived,
+				Topics:   project.Topics,
+			})
+		}
+		return false
+	}
+	// owner is either a group or a user, projects of the user are listed only if the group is not found
+	groupErr := p.list("groups/"+url.PathEscape(owner)+"/projects", url.Values{"include_subgroups": {"true"}}, newPage, appendPage)
+	if groupErr == nil {
+		return allRepos, nil
+	}
+	if !isNotFound(groupErr) {
+		return nil, groupErr
+	}
+	allRepos = nil
+	if err := p.list("users/"+url.PathEscape(owner)+"/projects", url.Values{}, newPage, appendPage); err != nil {
+		return nil, fmt.Errorf("%s is neither a group nor a user: %v, %v", owner, groupErr, err)
+	}
+	return allRepos, nil
+}
+
+func (p *gitlabProvider) ListOpenPullRequests(owner string, repo string) ([]*github.PullRequest, error) {
+	query := url.Values{"state": {"opened"}, "order_by": {"created_at"}, "sort": {"desc"}}
+	mrs, err := p.listMergeRequests(owner, repo, query, p.period.orConfigured().filterOpen)
+	if err != nil {
+		return nil, err
+	}
+	var allPRs []*github.PullRequest
+	for _, mr := range mrs {
+		allPRs = append(allPRs, mr.toPullRequest())
+	}
+	return allPRs, nil
+}
+
+func (p *gitlabProvider) ListClosedPullRequests(owner string, repo string) ([]*github.PullRequest, error) {
+	var allPRs []*github.PullRequest
+	for _, state := range []string{"merged", "closed"} {
+		query := url.Values{
+			"state":         {state},
+			"order_by":      {"updated_at"},
+			"sort":          {"desc"},
+			"updated_after": {p.period.orConfigured().begin.Format(time.RFC3339)},
+		}
+		mrs, err := p.listMergeRequests(owner, repo, query, p.period.orConfigured().filterClosed)
+		if err != nil {
+			return nil, err
+		}
+		for _, mr := range mrs {
+			allPRs = append(allPRs, mr.toPullRequest())
+		}
+	}
+	return allPRs, nil
+}
+
+func (p *gitlabProvider) ListUpdatedPullRequests(owner string, repo string, since time.Time) ([]*github.PullRequest, error) {
+	query := url.Values{
+		"state":         {"all"},
+		"order_by":      {"updated_at"},
+		"sort":          {"desc"},
+		"updated_after": {since.Format(time.RFC3339)},
+	}
+	mrs, err := p.listMergeRequests(owner, repo, query, filterUpdatedPullRequest(since))
+	if err != nil {
+		return nil, err
+	}
+	var allPRs []*github.PullRequest
+	for _, mr := range mrs {
+		allPRs = append(allPRs, mr.toPullRequest())
+	}
+	return allPRs, nil
+}
+
+// GetPullRequest gets a merge request with commits number and size computed from its commits and diffs.
+func (p *gitlabProvider) GetPullRequest(owner string, repo string, number int) (*github.PullRequest, error) {
+	mr, err := p.getMergeRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	pr := mr.toPullRequest()
+	commits, err := p.listMergeRequestCommits(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	files, err := p.ListFiles(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	commitsNumber := len(commits)
+	changedFiles := len(files)
+	var additions, deletions int
+	for _, f := range files {
+		additions += *f.Additions
+		deletions += *f.Deletions
+	}
+	pr.Commits = &commitsNumber
+	pr.ChangedFiles = &changedFiles
+	pr.Additions = &additions
+	pr.Deletions = &deletions
+	return pr, nil
+}
+
+// GetLabelNames gets labels of a merge request, with a LGTM label if the merge request was approved.
+func (p *gitlabProvider) GetLabelNames(owner string, repo string, number int) ([]string, error) {
+	mr, err := p.getMergeRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	labelNames := append([]string{}, mr.Labels...)
+	if !hasLGTMLabel(labelNames) {
+		approvals, err := p.listApprovalNotes(owner, repo, number)
+		if err != nil {
+			return nil, err
+		}
+		if len(approvals) != 0 {
+			labelNames = append(labelNames, LGTMLabels[0])
+		}
+	}
+	return labelNames, nil
+}
+
+// GetLatestLGTMEvent gets the first event that a LGTM label was added, or the first approval of a merge request.
+func (p *gitlabProvider) GetLatestLGTMEvent(owner string, repo string, number int) (*github.IssueEvent, error) {
+	var event *github.IssueEvent
+	err := p.list(fmt.Sprintf("%s/merge_requests/%d/resource_label_events", gitlabProjectPath(owner, repo), number), url.Values{},
+		func() interface{} {
+			return &[]*gitlabLabelEvent{}
+		},
+		func(v interface{}) bool {
+			for _, e := range *v.(*[]*gitlabLabelEvent) {
+				if e.Action == "add" && e.Label != nil && isLGTMLabel(e.Label.Name) {
+					name := e.Label.Name
+					createdAt := e.CreatedAt
+					labeled := "labeled"
+					event = &github.IssueEvent{Event: &labeled, CreatedAt: &createdAt, Label: &github.Label{Name: &name}}
+					return true
+				}
+			}
+			return false
+		})
+	if err != nil {
+		return nil, err
+	}
+	if event != nil {
+		return event, nil
+	}
+	approvals, err := p.listApprovalNotes(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	if len(approvals) != 0 {
+		approved := "approved"
+		createdAt := approvals[0].CreatedAt
+		return &github.IssueEvent{Event: &approved, CreatedAt: &createdAt}, nil
+	}
+	return nil, fmt.Errorf("no LGTM event found")
+}
+
+// ListFiles lists changed files of a merge request, lines added and removed are counted from diffs.
+func (p *gitlabProvider) ListFiles(owner string, repo string, number int) ([]*github.CommitFile, error) {
+	key := pullRequestKey(owner, repo, number)
+	if files, found := p.files[key]; found {
+		return files, nil
+	}
+	var allFiles []*github.CommitFile
+	err := p.list(fmt.Sprintf("%s/merge_requests/%d/diffs", gitlabProjectPath(owner, repo), number), url.Values{},
+		func() interface{} {
+			return &[]*gitlabDiff{}
+		},
+		func(v interface{}) bool {
+			for _, d := range *v.(*[]*gitlabDiff) {
+				filename := d.NewPath
+				additions, deletions := countDiffLines(d.Diff)
+				changes := additions + deletions
+				allFiles = append(allFiles, &github.CommitFile{
+					Filename:  &filename,
+					Additions: &additions,
+					Deletions: &deletions,
+					Changes:   &changes,
+				})
+			}
+			return false
+		})
+	if err != nil {
+		return nil, err
+	}
+	p.files[key] = allFiles
+	return allFiles, nil
+}
+
+// countDiffLines counts added and removed lines of a unified diff without file headers.
+func countDiffLines(diff string) (additions int, deletions int) {
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+") {
+			additions++
+		} else if strings.HasPrefix(line, "-") {
+			deletions++
+		}
+	}
+	return additions, deletions
+}
+
+func (p *gitlabProvider) GetClosedBy(owner string, repo string, number int) (string, error) {
+	mr, err := p.getMergeRequest(owner, repo, number)
+	if err != nil {
+		return "", err
+	}
+	if mr.ClosedBy != nil {
+		return mr.ClosedBy.Username, nil
+	}
+	return "", nil
+}
+
+// ListMergedCommits lists commits of merge requests created by author and merged in the period,
+// every commit is regarded as being merged when its merge request was merged.
+// listMergedMergeRequests lists merge requests of all authors merged in the period.
+func (p *gitlabProvider) listMergedMergeRequests(owner string, repo string) ([]*gitlabMergeRequest, error) {
+	key := owner + "/" + repo
+	if mrs, found := p.merged[key]; found {
+		return mrs, nil
+	}
+	query := url.Values{
+		"state":         {"merged"},
+		"order_by":      {"updated_at"},
+		"sort":          {"desc"},
+		"updated_after": {p.period.orConfigured().begin.Format(time.RFC3339)},
+	}
+	mrs, err := p.listMergeRequests(owner, repo, query, p.period.orConfigured().filterClosed)
+	if err != nil {
+		return nil, err
+	}
+	var merged []*gitlabMergeRequest
+	for _, mr := range mrs {
+		if mr.MergedAt != nil && p.period.orConfigured().contains(mr.MergedAt) {
+			merged = append(merged, mr)
+		}
+	}
+	p.merged[key] = merged
+	return merged, nil
+}
+
+// ListMergedCommits lists commits authored by author in merge requests of anyone merged in the period,
+// GitLab tells no account of commit authors, so they are mapped to users by emails of users.
+func (p *gitlabProvider) ListMergedCommits(owner string, repo string, author string) ([]*PullRequestCommit, error) {
+	mrs, err := p.listMergedMergeRequests(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var prCommits []*PullRequestCommit
+	for _, mr := range mrs {
+		commits, err := p.listMergeRequestCommits(owner, repo, mr.IID)
+		if err != nil {
+			return nil, err
+		}
+		var repositoryCommits []*github.RepositoryCommit
+		for _, c := range commits {
+			if commit := c.toRepositoryCommit(); authoredBy(p.host, commit, author) {
+				repositoryCommits = append(repositoryCommits, commit)
+			}
+		}
+		for _, c := range filterCommits(repositoryCommits) {
+			prCommits = append(prCommits, &PullRequestCommit{RepositoryCommit: c, Owner: owner, Repo: repo, MergedAt: mr.MergedAt,
+				PullRequest: mr.IID, Resolution: CommitResolutionPullRequest})
+		}
+	}
+	return prCommits, nil
+}
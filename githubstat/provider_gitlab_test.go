@@ -0,0 +1,196 @@
+package githubstat
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_countDiffLines(t *testing.T) {
+	cases := []struct {
+		diff                 string
+		additions, deletions int
+	}{
+		{"", 0, 0},
+		{"@@ -0,0 +1,2 @@\n+package a\n+\n", 2, 0},
+		{"@@ -1,3 +1,3 @@\n package a\n-const A = 1\n+const A = 2\n \n", 1, 1},
+		{"@@ -1,2 +0,0 @@\n-package a\n-\n\\ No newline at end of file\n", 0, 2},
+	}
+	for _, c := range cases {
+		if additions, deletions := countDiffLines(c.diff); additions != c.additions || deletions != c.deletions {
+			t.Errorf("countDiffLines(%q) = %d, %d, want %d, %d", c.diff, additions, deletions, c.additions, c.deletions)
+		}
+	}
+}
+
+// newMergeRequestFakeGitLab serves merge requests of bruceauyeung and tanshanshan, the stat period is from 2016-10-01
+// to 2016-12-30.
+func newMergeRequestFakeGitLab(t *testing.T) *fakeGitLab {
+	f := newFakeGitLab(t)
+	f.addMergeRequest(3, "bruceauyeung", "opened", "2016-12-26T00:00:00Z", "2016-12-26T00:00:00Z", "kind/bug")
+	f.labelEvents[3] = []*gitlabLabelEvent{
+		{Action: "add", CreatedAt: *fakeTime("2016-12-26T00:00:00Z"), Label: &struct {
+			Name string `json:"name"`
+		}{"kind/bug"}},
+		{Action: "add", CreatedAt: *fakeTime("2016-12-27T00:00:00Z"), Label: &struct {
+			Name string `json:"name"`
+		}{"LGTM"}},
+	}
+	f.addMergeRequest(1, "tanshanshan", "opened", "2016-11-01T00:00:00Z", "2016-11-02T00:00:00Z")
+	// a comment saying the same words is not an approval
+	f.notes[1] = []*gitlabNote{
+		{Body: gitlabApprovedNote, Author: &gitlabUser{Username: "tanshanshan"}, CreatedAt: *fakeTime("2016-11-01T00:00:00Z")},
+		{Body: "added 1 commit", System: true, CreatedAt: *fakeTime("2016-11-01T12:00:00Z")},
+		{Body: gitlabApprovedNote, System: true, Author: &gitlabUser{Username: "bruceauyeung"}, CreatedAt: *fakeTime("2016-11-02T00:00:00Z")},
+	}
+	f.addMergeRequest(2, "bruceauyeung", "opened", "2016-09-01T00:00:00Z", "2016-09-01T00:00:00Z")
+	f.addMergeRequest(4, "bruceauyeung", "merged", "2016-10-02T00:00:00Z", "2016-10-05T00:00:00Z")
+	f.commits[4] = []*gitlabCommit{
+		{ID: "aaa", Message: "add a.go", AuthorName: "bruce", AuthorEmail: "bruce@example.com", CreatedAt: *fakeTime("2016-10-02T00:00:00Z")},
+		{ID: "bbb", Message: "Merge branch 'master' into add-a", CreatedAt: *fakeTime("2016-10-03T00:00:00Z")},
+		{ID: "ccc", Message: "add b.go", AuthorName: "shanshan", AuthorEmail: "Shanshan@Example.com", CreatedAt: *fakeTime("2016-10-04T00:00:00Z")},
+	}
+	f.diffs[4] = []*gitlabDiff{
+		{NewPath: "a.go", Diff: "@@ -0,0 +1,3 @@\n+package a\n+\n+const A = 1\n"},
+		{NewPath: "b.go", Diff: "@@ -1 +1 @@\n-package a\n+package b\n"},
+	}
+	f.addMergeRequest(5, "tanshanshan", "closed", "2016-10-10T00:00:00Z", "2016-11-01T00:00:00Z").ClosedBy = &gitlabUser{Username: "bruceauyeung"}
+	f.addMergeRequest(6, "bruceauyeung", "merged", "2016-08-01T00:00:00Z", "2016-09-10T00:00:00Z")
+	return f
+}
+
+func Test_gitlabProvider(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	users := Config.Users
+	defer func() { Config.Users = users }()
+	Config.Users = []User{{Name: "bruceauyeung", Emails: []string{"bruce@example.com"}},
+		{Name: "tanshanshan", Emails: []string{"shanshan@example.com"}}}
+	f := newMergeRequestFakeGitLab(t)
+	defer f.Close()
+	p := f.provider()
+
+	// MR 2 created before stat period stops listing on the second page
+	open, err := p.ListOpenPullRequests("kubernetes", "kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numbers := pullRequestNumbers(open); !equalInts(numbers, []int{3, 1}) {
+		t.Errorf("open MRs are %v, want [3 1]", numbers)
+	}
+	if len(f.requests) != 2 || !strings.HasPrefix(f.requests[0], "/api/v4/projects/kubernetes%2Fkubernetes/") {
+		t.Errorf("requests of open MRs are %v", f.requests)
+	}
+	// MR 6 updated before stat period is not listed
+	closed, err := p.ListClosedPullRequests("kubernetes", "kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numbers := pullRequestNumbers(closed); !equalInts(numbers, []int{4, 5}) {
+		t.Errorf("closed MRs are %v, want [4 5]", numbers)
+	}
+	if pr := closed[0]; *pr.State != "closed" || pr.MergedAt == nil || !pr.ClosedAt.Equal(*pr.MergedAt) ||
+		*pr.User.Login != "bruceauyeung" || !strings.HasSuffix(*pr.HTMLURL, "/merge_requests/4") {
+		t.Errorf("merged MR is %+v", pr)
+	}
+
+	pr, err := p.GetPullRequest("kubernetes", "kubernetes", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *pr.Commits != 3 || *pr.ChangedFiles != 2 || *pr.Additions != 4 || *pr.Deletions != 1 {
+		t.Errorf("MR 4 has %d commits, %d files, +%d -%d, want 3 commits, 2 files, +4 -1",
+			*pr.Commits, *pr.ChangedFiles, *pr.Additions, *pr.Deletions)
+	}
+
+	// approvals are parsed from system notes only
+	if labels, err := p.GetLabelNames("kubernetes", "kubernetes", 1); err != nil || len(labels) != 1 || labels[0] != LGTMLabels[0] {
+		t.Errorf("labels of MR 1 are %v, %v, want [%s]", labels, err, LGTMLabels[0])
+	}
+	event, err := p.GetLatestLGTMEvent("kubernetes", "kubernetes", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *event.Event != "approved" || !event.CreatedAt.Equal(*fakeTime("2016-11-02T00:00:00Z")) {
+		t.Errorf("LGTM event of MR 1 is %s at %v, want approved at 2016-11-02", *event.Event, event.CreatedAt)
+	}
+	// LGTM labels are preferred to approvals
+	event, err = p.GetLatestLGTMEvent("kubernetes", "kubernetes", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *event.Label.Name != "LGTM" || !event.CreatedAt.Equal(*fakeTime("2016-12-27T00:00:00Z")) {
+		t.Errorf("LGTM event of MR 3 is %s at %v, want LGTM at 2016-12-27", *event.Label.Name, event.CreatedAt)
+	}
+	if labels, err := p.GetLabelNames("kubernetes", "kubernetes", 3); err != nil || len(labels) != 1 || labels[0] != "kind/bug" {
+		t.Errorf("labels of MR 3 are %v, %v, want [kind/bug]", labels, err)
+	}
+	if _, err := p.GetLatestLGTMEvent("kubernetes", "kubernetes", 5); err == nil {
+		t.Errorf("MR 5 should have no LGTM event")
+	}
+	if closedBy, err := p.GetClosedBy("kubernetes", "kubernetes", 5); err != nil || closedBy != "bruceauyeung" {
+		t.Errorf("MR 5 is closed by %q, %v, want bruceauyeung", closedBy, err)
+	}
+
+	// commits are of users of their author emails, merge commits from master are filtered out
+	commits, err := p.ListMergedCommits("kubernetes", "kubernetes", "bruceauyeung")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || *commits[0].RepositoryCommit.SHA != "aaa" || commits[0].PullRequest != 4 ||
+		!commits[0].MergedAt.Equal(*fakeTime("2016-10-05T00:00:00Z")) || *commits[0].RepositoryCommit.Commit.Author.Email != "bruce@example.com" {
+		t.Errorf("merged commits are %+v", commits)
+	}
+	// tanshanshan's commit in MR 4 of bruceauyeung, merge requests and their commits are listed once for all users
+	requests := len(f.requests)
+	if commits, err := p.ListMergedCommits("kubernetes", "kubernetes", "tanshanshan"); err != nil || len(commits) != 1 ||
+		*commits[0].RepositoryCommit.SHA != "ccc" || commits[0].PullRequest != 4 {
+		t.Errorf("merged commits of tanshanshan are %+v, %v, want ccc of MR 4", commits, err)
+	}
+	if len(f.requests) != requests {
+		t.Errorf("merge requests or commits are listed again: %v", f.requests[requests:])
+	}
+}
+
+func Test_gitlabProviderListRepositories(t *testing.T) {
+	f := newFakeGitLab(t)
+	defer f.Close()
+	for _, name := range []string{"kubernetes", "website", "charts"} {
+		project := &gitlabProject{Path: name, Visibility: "public"}
+		project.Namespace.FullPath = "kubernetes"
+		f.groups["kubernetes"] = append(f.groups["kubernetes"], project)
+	}
+	f.groups["kubernetes"][1].ForkedFromProject = &struct{}{}
+	f.groups["kubernetes"][2].Archived = true
+	mine := &gitlabProject{Path: "dotfiles", Visibility: "private"}
+	mine.Namespace.FullPath = "bruceauyeung"
+	f.users["bruceauyeung"] = []*gitlabProject{mine}
+	p := f.provider()
+
+	// projects of groups are paged
+	repos, err := p.ListRepositories("kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 3 || *repos[0].Owner.Login != "kubernetes" || *repos[2].Name != "charts" ||
+		!*repos[1].Fork || !*repos[2].Archived || *repos[0].Private {
+		t.Errorf("repos of group kubernetes are %+v", repos)
+	}
+	// owners which are not groups are users
+	repos, err = p.ListRepositories("bruceauyeung")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 1 || *repos[0].Name != "dotfiles" || !*repos[0].Private {
+		t.Errorf("repos of user bruceauyeung are %+v", repos)
+	}
+	// errors other than not found are not taken as users
+	requests := len(f.requests)
+	if _, err := p.ListRepositories("broken"); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("listing repos of a broken group should fail with its error, got %v", err)
+	}
+	if len(f.requests) != requests+1 {
+		t.Errorf("projects of user broken should not be listed, requests are %v", f.requests[requests:])
+	}
+	if _, err := p.ListRepositories("nobody"); err == nil || !strings.Contains(err.Error(), "neither a group nor a user") {
+		t.Errorf("listing repos of nobody should fail, got %v", err)
+	}
+}
@@ -0,0 +1,482 @@
+package githubstat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const DefaultGraphQLURL = "https://api.github.com/graphql"
+
+// connections of PRs, their first pages are fetched with PRs, and the rest by graphqlPullRequestPagesQuery
+const (
+	graphqlLabelsConnection = `labels(first: 100%s) {
+    pageInfo { hasNextPage endCursor }
+    nodes { name }
+  }`
+	graphqlTimelineItemsConnection = `timelineItems(first: 100%s, itemTypes: [LABELED_EVENT, CLOSED_EVENT]) {
+    pageInfo { hasNextPage endCursor }
+    nodes {
+      __typename
+      ... on LabeledEvent { createdAt label { name } }
+      ... on ClosedEvent { createdAt actor { login } }
+    }
+  }`
+	graphqlReviewsConnection = `reviews(first: 100%s, states: [APPROVED]) {
+    pageInfo { hasNextPage endCursor }
+    nodes { submittedAt author { login } }
+  }`
+)
+
+var graphqlPullRequestFields = `
+fragment pullRequestFields on PullRequest {
+  number title url state createdAt updatedAt closedAt mergedAt
+  additions deletions changedFiles
+  author { login }
+  commits { totalCount }
+  ` + fmt.Sprintf(graphqlLabelsConnection, "") + `
+  ` + fmt.Sprintf(graphqlTimelineItemsConnection, "") + `
+  ` + fmt.Sprintf(graphqlReviewsConnection, "") + `
+}`
+
+// graphqlPullRequestPagesQuery queries the page of a connection of a PR after $cursor.
+const graphqlPullRequestPagesQuery = `
+query($owner: String!, $name: String!, $number: Int!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      %s
+    }
+  }
+  rateLimit { limit remaining resetAt }
+}`
+
+var graphqlListPullRequestsQuery = `
+query($owner: String!, $name: String!, $states: [PullRequestState!], $field: IssueOrderField!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequests(first: 50, after: $cursor, states: $states, orderBy: {field: $field, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes { ...pullRequestFields }
+    }
+  }
+  rateLimit { limit remaining resetAt }
+}` + graphqlPullRequestFields
+
+var graphqlGetPullRequestQuery = `
+query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) { ...pullRequestFields }
+  }
+  rateLimit { limit remaining resetAt }
+}` + graphqlPullRequestFields
+
+const graphqlListFilesQuery = `
+query($owner: String!, $name: String!, $number: Int!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      files(first: 100, after: $cursor) {
+        pageInfo { hasNextPage endCursor }
+        nodes { path additions deletions }
+      }
+    }
+  }
+  rateLimit { limit remaining resetAt }
+}`
+
+type graphqlLogin struct {
+	Login string `json:"login"`
+}
+
+type graphqlPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type graphqlRateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+type graphqlPullRequest struct {
+	Number       int           `json:"number"`
+	Title        string        `json:"title"`
+	URL          string        `json:"url"`
+	State        string        `json:"state"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+	ClosedAt     *time.Time    `json:"closedAt"`
+	MergedAt     *time.Time    `json:"mergedAt"`
+	Additions    int           `json:"additions"`
+	Deletions    int           `json:"deletions"`
+	ChangedFiles int           `json:"changedFiles"`
+	Author       *graphqlLogin `json:"author"`
+	Commits      struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"commits"`
+	Labels struct {
+		PageInfo graphqlPageInfo `json:"pageInfo"`
+		Nodes    []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	TimelineItems struct {
+		PageInfo graphqlPageInfo `json:"pageInfo"`
+		Nodes    []struct {
+			Typename  string        `json:"__typename"`
+			CreatedAt time.Time     `json:"createdAt"`
+			Actor     *graphqlLogin `json:"actor"`
+			Label     *struct {
+				Name string `json:"name"`
+			} `json:"label"`
+		} `json:"nodes"`
+	} `json:"timelineItems"`
+	// approved reviews
+	Reviews struct {
+		PageInfo graphqlPageInfo `json:"pageInfo"`
+		Nodes    []struct {
+			SubmittedAt time.Time     `json:"submittedAt"`
+			Author      *graphqlLogin `json:"author"`
+		} `json:"nodes"`
+	} `json:"reviews"`
+}
+
+// toPullRequest maps a GraphQL PR onto the PR of REST API.
+func (pr *graphqlPullRequest) toPullRequest() *github.PullRequest {
+	number := pr.Number
+	title := pr.Title
+	url := pr.URL
+	state := strings.ToLower(pr.State)
+	if state == "merged" {
+		state = "closed"
+	}
+	createdAt := pr.CreatedAt
+	updatedAt := pr.UpdatedAt
+	commits := pr.Commits.TotalCount
+	additions := pr.Additions
+	deletions := pr.Deletions
+	changedFiles := pr.ChangedFiles
+	p := &github.PullRequest{
+		Number:       &number,
+		Title:        &title,
+		HTMLURL:      &url,
+		State:        &state,
+		CreatedAt:    &createdAt,
+		UpdatedAt:    &updatedAt,
+		ClosedAt:     pr.ClosedAt,
+		MergedAt:     pr.MergedAt,
+		Commits:      &commits,
+		Additions:    &additions,
+		Deletions:    &deletions,
+		ChangedFiles: &changedFiles,
+	}
+	if pr.Author != nil {
+		login := pr.Author.Login
+		p.User = &github.User{Login: &login}
+	}
+	return p
+}
+
+// graphqlProvider fetches PRs with their labels, commits count, label events and approved reviews in bulk
+// by GitHub GraphQL API (v4). stackalytics style commits are still fetched by REST API
+// because they depend on the search API.
+type graphqlProvider struct {
+	*restProvider
+	httpClient *http.Client
+	url        string
+	// PRs fetched by listing, keyed by "owner/repo#number", so that no more request is needed per PR.
+	cache map[string]*graphqlPullRequest
+}
+
+func newGraphQLProvider(proxyClient *ProxyClient, period statPeriod) *graphqlProvider {
+	return &graphqlProvider{
+		restProvider: &restProvider{client: proxyClient.getClient(), period: period},
+		httpClient:   proxyClient.getHTTPClient(),
+		url:          proxyClient.getGraphQLURL(),
+		cache:        make(map[string]*graphqlPullRequest),
+	}
+}
+
+// query posts a GraphQL query and decodes "data" of response into result.
+func (p *graphqlProvider) query(query string, variables map[string]interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	resp, err := p.httpClient.Post(p.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("graphql query failed: %s", resp.Status)
+	}
+	var r struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return err
+	}
+	if len(r.Errors) != 0 {
+		return fmt.Errorf("graphql query failed: %s", r.Errors[0].Message)
+	}
+	if err := json.Unmarshal(r.Data, result); err != nil {
+		return err
+	}
+
+	var rate struct {
+		RateLimit *graphqlRateLimit `json:"rateLimit"`
+	}
+	if json.Unmarshal(r.Data, &rate) == nil && rate.RateLimit != nil && rate.RateLimit.Remaining == 0 {
+		secsToSleep := rate.RateLimit.ResetAt.Unix() - time.Now().Unix()
+		fmt.Printf("graphql rate limit is %v, current rate limit window remains %v, sleep %v seconds for the next rate limit window\n",
+			rate.RateLimit.Limit,
+			0,
+			secsToSleep)
+		time.Sleep(time.Duration(secsToSleep) * time.Second)
+	}
+	return nil
+}
+
+// listPullRequests lists PRs page by page, filter tells whether a PR is kept and whether listing should stop.
+func (p *graphqlProvider) listPullRequests(owner string, repo string, states []string, field string,
+	filter func(*github.PullRequest) (bool, bool)) ([]*github.PullRequest, error) {
+	variables := map[string]interface{}{
+		"owner":  owner,
+		"name":   repo,
+		"states": states,
+		"field":  field,
+	}
+	var allPRs []*github.PullRequest
+	page := 1
+loop:
+	for {
+		var result struct {
+			Repository struct {
+				PullRequests struct {
+					PageInfo graphqlPageInfo       `json:"pageInfo"`
+					Nodes    []*graphqlPullRequest `json:"nodes"`
+				} `json:"pullRequests"`
+			} `json:"repository"`
+		}
+		if err := p.query(graphqlListPullRequestsQuery, variables, &result); err != nil {
+			return nil, err
+		}
+		fmt.Printf("page:%d fin\n", page)
+		for _, node := range result.Repository.PullRequests.Nodes {
+			pr := node.toPullRequest()
+			keep, stop := filter(pr)
+			if stop {
+				break loop
+			}
+			if keep {
+				p.cache[pullRequestKey(owner, repo, node.Number)] = node
+				allPRs = append(allPRs, pr)
+			}
+		}
+		pageInfo := result.Repository.PullRequests.PageInfo
+		if !pageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = pageInfo.EndCursor
+		page++
+	}
+	return allPRs, nil
+}
+
+// getGraphQLPullRequest gets a PR from cache, or queries it if it was not listed.
+// connections of the PR are completed by their remaining pages.
+func (p *graphqlProvider) getGraphQLPullRequest(owner string, repo string, number int) (*graphqlPullRequest, error) {
+	key := pullRequestKey(owner, repo, number)
+	if pr, found := p.cache[key]; found {
+		return pr, p.completePullRequest(owner, repo, pr)
+	}
+	var result struct {
+		Repository struct {
+			PullRequest *graphqlPullRequest `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	variables := map[string]interface{}{"owner": owner, "name": repo, "number": number}
+	if err := p.query(graphqlGetPullRequestQuery, variables, &result); err != nil {
+		return nil, err
+	}
+	if result.Repository.PullRequest == nil {
+		return nil, fmt.Errorf("pull request %s not found", key)
+	}
+	p.cache[key] = result.Repository.PullRequest
+	return result.Repository.PullRequest, p.completePullRequest(owner, repo, result.Repository.PullRequest)
+}
+
+// completePullRequest queries the remaining pages of labels, timeline items and reviews of a PR,
+// which only busy PRs have.
+func (p *graphqlProvider) completePullRequest(owner string, repo string, pr *graphqlPullRequest) error {
+	err := p.queryPages(owner, repo, pr.Number, graphqlLabelsConnection, &pr.Labels.PageInfo, func(page *graphqlPullRequest) graphqlPageInfo {
+		pr.Labels.Nodes = append(pr.Labels.Nodes, page.Labels.Nodes...)
+		return page.Labels.PageInfo
+	})
+	if err != nil {
+		return err
+	}
+	err = p.queryPages(owner, repo, pr.Number, graphqlTimelineItemsConnection, &pr.TimelineItems.PageInfo, func(page *graphqlPullRequest) graphqlPageInfo {
+		pr.TimelineItems.Nodes = append(pr.TimelineItems.Nodes, page.TimelineItems.Nodes...)
+		return page.TimelineItems.PageInfo
+	})
+	if err != nil {
+		return err
+	}
+	return p.queryPages(owner, repo, pr.Number, graphqlReviewsConnection, &pr.Reviews.PageInfo, func(page *graphqlPullRequest) graphqlPageInfo {
+		pr.Reviews.Nodes = append(pr.Reviews.Nodes, page.Reviews.Nodes...)
+		return page.Reviews.PageInfo
+	})
+}
+
+// queryPages queries pages of a connection of a PR after pageInfo until the last one, add appends nodes of
+// the connection in a page and returns its page info. pageInfo is updated by every page, so that pages are
+// only queried once.
+func (p *graphqlProvider) queryPages(owner string, repo string, number int, connection string, pageInfo *graphqlPageInfo,
+	add func(page *graphqlPullRequest) graphqlPageInfo) error {
+	query := fmt.Sprintf(graphqlPullRequestPagesQuery, fmt.Sprintf(connection, ", after: $cursor"))
+	variables := map[string]interface{}{"owner": owner, "name": repo, "number": number}
+	for pageInfo.HasNextPage {
+		variables["cursor"] = pageInfo.EndCursor
+		var result struct {
+			Repository struct {
+				PullRequest *graphqlPullRequest `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		if err := p.query(query, variables, &result); err != nil {
+			return err
+		}
+		if result.Repository.PullRequest == nil {
+			return fmt.Errorf("pull request %s not found", pullRequestKey(owner, repo, number))
+		}
+		*pageInfo = add(result.Repository.PullRequest)
+	}
+	return nil
+}
+
+func (p *graphqlProvider) ListOpenPullRequests(owner string, repo string) ([]*github.PullRequest, error) {
+	return p.listPullRequests(owner, repo, []string{"OPEN"}, "CREATED_AT", p.period.orConfigured().filterOpen)
+}
+
+func (p *graphqlProvider) ListClosedPullRequests(owner string, repo string) ([]*github.PullRequest, error) {
+	return p.listPullRequests(owner, repo, []string{"CLOSED", "MERGED"}, "UPDATED_AT", p.period.orConfigured().filterClosed)
+}
+
+func (p *graphqlProvider) ListUpdatedPullRequests(owner string, repo string, since time.Time) ([]*github.PullRequest, error) {
+	return p.listPullRequests(owner, repo, []string{"OPEN", "CLOSED", "MERGED"}, "UPDATED_AT", filterUpdatedPullRequest(since))
+}
+
+func (p *graphqlProvider) GetPullRequest(owner string, repo string, number int) (*github.PullRequest, error) {
+	pr, err := p.getGraphQLPullRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	return pr.toPullRequest(), nil
+}
+
+func (p *graphqlProvider) GetLabelNames(owner string, repo string, number int) ([]string, error) {
+	pr, err := p.getGraphQLPullRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var labelNames []string
+	for _, l := range pr.Labels.Nodes {
+		labelNames = append(labelNames, l.Name)
+	}
+	return labelNames, nil
+}
+
+// GetLatestLGTMEvent gets the latest labeled event of LGTM labels, timeline items are in time order.
+// the latest approved review is taken as the LGTM event if there is no labeled event, e.g. the label was
+// added when the PR was created.
+func (p *graphqlProvider) GetLatestLGTMEvent(owner string, repo string, number int) (*github.IssueEvent, error) {
+	pr, err := p.getGraphQLPullRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	items := pr.TimelineItems.Nodes
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		if item.Typename == "LabeledEvent" && item.Label != nil && isLGTMLabel(item.Label.Name) {
+			event := "labeled"
+			name := item.Label.Name
+			createdAt := item.CreatedAt
+			return &github.IssueEvent{
+				Event:     &event,
+				CreatedAt: &createdAt,
+				Label:     &github.Label{Name: &name},
+			}, nil
+		}
+	}
+	if reviews := pr.Reviews.Nodes; len(reviews) != 0 {
+		event := "approved"
+		submittedAt := reviews[len(reviews)-1].SubmittedAt
+		return &github.IssueEvent{Event: &event, CreatedAt: &submittedAt}, nil
+	}
+	return nil, fmt.Errorf("no LGTM event found")
+}
+
+func (p *graphqlProvider) ListFiles(owner string, repo string, number int) ([]*github.CommitFile, error) {
+	variables := map[string]interface{}{"owner": owner, "name": repo, "number": number}
+	var allFiles []*github.CommitFile
+	for {
+		var result struct {
+			Repository struct {
+				PullRequest struct {
+					Files struct {
+						PageInfo graphqlPageInfo `json:"pageInfo"`
+						Nodes    []struct {
+							Path      string `json:"path"`
+							Additions int    `json:"additions"`
+							Deletions int    `json:"deletions"`
+						} `json:"nodes"`
+					} `json:"files"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		if err := p.query(graphqlListFilesQuery, variables, &result); err != nil {
+			return nil, err
+		}
+		files := result.Repository.PullRequest.Files
+		for _, f := range files.Nodes {
+			filename := f.Path
+			additions := f.Additions
+			deletions := f.Deletions
+			changes := additions + deletions
+			allFiles = append(allFiles, &github.CommitFile{
+				Filename:  &filename,
+				Additions: &additions,
+				Deletions: &deletions,
+				Changes:   &changes,
+			})
+		}
+		if !files.PageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = files.PageInfo.EndCursor
+	}
+	return allFiles, nil
+}
+
+// GetClosedBy gets actor of the last closed event of a PR.
+func (p *graphqlProvider) GetClosedBy(owner string, repo string, number int) (string, error) {
+	pr, err := p.getGraphQLPullRequest(owner, repo, number)
+	if err != nil {
+		return "", err
+	}
+	var closedBy string
+	for _, item := range pr.TimelineItems.Nodes {
+		if item.Typename == "ClosedEvent" && item.Actor != nil {
+			closedBy = item.Actor.Login
+		}
+	}
+	return closedBy, nil
+}
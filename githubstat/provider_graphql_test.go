@@ -0,0 +1,156 @@
+package githubstat
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// graphqlProvider returns a GraphQL provider which queries the fake server.
+func (f *fakeGitHub) graphqlProvider() *graphqlProvider {
+	return &graphqlProvider{
+		restProvider: &restProvider{client: f.client()},
+		httpClient:   http.DefaultClient,
+		url:          f.URL + "/graphql",
+		cache:        make(map[string]*graphqlPullRequest),
+	}
+}
+
+func Test_graphqlProvider(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+	f.closedBy[3] = "bruceauyeung"
+	p := f.graphqlProvider()
+
+	// PRs are listed in the same order as REST API
+	open, err := p.ListOpenPullRequests("kubernetes", "kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numbers := pullRequestNumbers(open); !equalInts(numbers, []int{9, 8, 7}) {
+		t.Errorf("open PRs are %v, want [9 8 7]", numbers)
+	}
+	// 5 open PRs are paged by 2, listing stops at PR 6 created before stat period on the third page
+	if len(f.graphqlVariables) != 3 || f.graphqlVariables[0]["cursor"] != nil || f.graphqlVariables[2]["cursor"] != "4" ||
+		f.graphqlVariables[0]["field"] != "CREATED_AT" {
+		t.Errorf("variables of open PR queries are %v", f.graphqlVariables)
+	}
+	closed, err := p.ListClosedPullRequests("kubernetes", "kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numbers := pullRequestNumbers(closed); !equalInts(numbers, []int{5, 3, 4}) {
+		t.Errorf("closed PRs are %v, want [5 3 4]", numbers)
+	}
+	if states := f.graphqlVariables[3]["states"]; len(states.([]interface{})) != 2 || f.graphqlVariables[3]["field"] != "UPDATED_AT" {
+		t.Errorf("variables of closed PR queries are %v", f.graphqlVariables[3])
+	}
+
+	// merged PRs are closed PRs of REST API
+	if pr := closed[0]; *pr.State != "closed" || pr.MergedAt == nil || *pr.User.Login != "bruceauyeung" ||
+		*pr.HTMLURL != "https://github.com/kubernetes/kubernetes/pull/5" {
+		t.Errorf("merged PR is %+v", pr)
+	}
+	if pr := closed[1]; *pr.State != "closed" || pr.MergedAt != nil || !pr.ClosedAt.Equal(*fakeTime("2016-11-05T00:00:00Z")) {
+		t.Errorf("closed unmerged PR is %+v", pr)
+	}
+
+	// listed PRs are got from cache without more queries
+	queries := len(f.graphqlVariables)
+	labels, err := p.GetLabelNames("kubernetes", "kubernetes", 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 1 || labels[0] != "lgtm" {
+		t.Errorf("labels of PR 9 are %v, want [lgtm]", labels)
+	}
+	event, err := p.GetLatestLGTMEvent("kubernetes", "kubernetes", 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *event.Label.Name != "lgtm" || !event.CreatedAt.Equal(*fakeTime("2016-12-27T00:00:00Z")) {
+		t.Errorf("LGTM event is %s at %v, want lgtm at 2016-12-27", *event.Label.Name, event.CreatedAt)
+	}
+	if closedBy, err := p.GetClosedBy("kubernetes", "kubernetes", 3); err != nil || closedBy != "bruceauyeung" {
+		t.Errorf("PR 3 is closed by %q, %v, want bruceauyeung", closedBy, err)
+	}
+	if len(f.graphqlVariables) != queries {
+		t.Errorf("%d more queries for listed PRs, want none", len(f.graphqlVariables)-queries)
+	}
+	// PRs which were not listed are queried one by one
+	if pr, err := p.GetPullRequest("kubernetes", "kubernetes", 2); err != nil || *pr.Number != 2 {
+		t.Errorf("PR 2 is %+v, %v", pr, err)
+	}
+	if _, err := p.GetPullRequest("kubernetes", "kubernetes", 100); err == nil {
+		t.Errorf("PR 100 should not be found")
+	}
+
+	// labels and timeline items beyond the first page are queried page by page, the latest LGTM event is taken
+	// when the LGTM label was added again
+	f.addPullRequest(20, "tanshanshan", "open", "2016-12-01T00:00:00Z", "2016-12-01T00:00:00Z", "")
+	for i, label := range []string{"kind/bug", "lgtm", "size/XS", "lgtm", "area/api"} {
+		f.addLabel(20, label, fmt.Sprintf("2016-12-0%dT00:00:00Z", i+2))
+	}
+	queries = len(f.graphqlVariables)
+	if labels, err := p.GetLabelNames("kubernetes", "kubernetes", 20); err != nil || len(labels) != 5 {
+		t.Errorf("labels of PR 20 are %v, %v, want 5 labels", labels, err)
+	}
+	// one query of the PR, 2 more pages of labels and of timeline items
+	if len(f.graphqlVariables) != queries+5 || f.graphqlVariables[queries+1]["cursor"] != "2" {
+		t.Errorf("%d queries of PR 20, want 5: %v", len(f.graphqlVariables)-queries, f.graphqlVariables[queries:])
+	}
+	event, err = p.GetLatestLGTMEvent("kubernetes", "kubernetes", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !event.CreatedAt.Equal(*fakeTime("2016-12-05T00:00:00Z")) || len(f.graphqlVariables) != queries+5 {
+		t.Errorf("LGTM event of PR 20 is at %v, want the latest at 2016-12-05", event.CreatedAt)
+	}
+
+	// approved reviews are LGTM events of PRs whose LGTM labels have no labeled event
+	f.addPullRequest(21, "tanshanshan", "open", "2016-12-01T00:00:00Z", "2016-12-01T00:00:00Z", "")
+	f.labels[21] = []string{"lgtm"}
+	f.approvals[21] = []string{"2016-12-02T00:00:00Z", "2016-12-03T00:00:00Z", "2016-12-04T00:00:00Z"}
+	event, err = p.GetLatestLGTMEvent("kubernetes", "kubernetes", 21)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *event.Event != "approved" || !event.CreatedAt.Equal(*fakeTime("2016-12-04T00:00:00Z")) {
+		t.Errorf("LGTM event of PR 21 is %s at %v, want approved at 2016-12-04", *event.Event, event.CreatedAt)
+	}
+}
+
+func Test_apiComparison(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	users := Config.Users
+	defer func() { Config.Users = users }()
+	Config.Users = []User{{Name: "bruceauyeung"}, {Name: "tanshanshan"}}
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+
+	repo, err := ParseRepo("kubernetes/kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetch := func(provider Provider) *AllPullRequestMetrics {
+		m := &PullRequestMetricsRequest{providers: map[string]Provider{"": provider}}
+		m.SetParameters(&MetricsParameters{Repos: []*RepoParameters{repo}})
+		return m.FetchMetrics().(*AllPullRequestMetrics)
+	}
+	rest, graphql := fetch(&restProvider{client: f.client()}), fetch(f.graphqlProvider())
+	if rows, differences := apiComparisonRows(rest.Overall, graphql.Overall); differences != 0 || len(rows) != 2 {
+		t.Errorf("overall metrics differ between rest and graphql api: %v", rows)
+	}
+	if rows, differences := apiComparisonRows(rest.Week, graphql.Week); differences != 0 {
+		t.Errorf("week metrics differ between rest and graphql api: %v", rows)
+	}
+
+	// metrics only fetched by one api are compared with zeros
+	graphql.Overall[0].Merged++
+	graphql.Overall = append(graphql.Overall, &PullRequestMetrics{User: "newcomer", Repo: "kubernetes/kubernetes", Created: 1})
+	rows, differences := apiComparisonRows(rest.Overall, graphql.Overall)
+	if differences != 2 || len(rows) != 3 || rows[0][2] != "2 / 3" || rows[2][0] != "newcomer" || rows[2][6] != "0 / 1" {
+		t.Errorf("comparison is %v with %d differences", rows, differences)
+	}
+}
@@ -0,0 +1,214 @@
+package githubstat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ReportFormatSlack      = "slack"
+	ReportFormatMattermost = "mattermost"
+
+	// slack rejects text of a section longer than 3000 characters
+	slackSectionLimit = 3000
+)
+
+type ReportConfig struct {
+	Schedule   string // cron expression, e.g. "0 17 * * 5", reports are sent once if it is empty
+	WebhookURL string // incoming webhook of Slack or Mattermost, nothing is posted if it is empty
+	Format     string // "slack" (default) or "mattermost"
+	Email      EmailConfig
+}
+
+// reportSection is the metrics of a window merged by user.
+type reportSection struct {
+	Title   string
+	Metrics []*PullRequestMetrics
+}
+
+// Report fetches metrics of repos and sends them to the incoming webhook and by email on Config.Report.Schedule.
+// if dryRun is true, the report is fetched at once and printed instead of being sent.
+func Report(repos []*RepoParameters, dryRun bool) {
+	if Config.Report.WebhookURL == "" && Config.Report.Email.Host == "" {
+		panic("neither webhookURL nor email host of report is configured")
+	}
+	if dryRun || Config.Report.Schedule == "" {
+		sendReport(repos, dryRun)
+		return
+	}
+	schedule, err := parseCron(Config.Report.Schedule)
+	if err != nil {
+		panic(err)
+	}
+	for {
+		next := schedule.next(time.Now())
+		if next.IsZero() {
+			panic(fmt.Sprintf("schedule %q never comes", Config.Report.Schedule))
+		}
+		fmt.Printf("next report is sent at %v\n", next)
+		time.Sleep(time.Until(next))
+		func() {
+			// a failed report does not stop the schedule
+			defer func() {
+				if r := recover(); r != nil {
+					fmt.Printf("failed to send report : %v\n", r)
+				}
+			}()
+			sendReport(repos, false)
+		}()
+	}
+}
+
+func sendReport(repos []*RepoParameters, dryRun bool) {
+	start := time.Now()
+	// this week is of every report, the configured one is left untouched like the server does
+	weekFirstDay := getWeekFirstDay(start)
+	sections := reportSections(fetchPullRequestMetrics(repos, weekFirstDay), start, weekFirstDay)
+	if Config.Report.WebhookURL != "" {
+		payload, err := newChatPayload(Config.Report.Format, sections)
+		if err != nil {
+			panic(err)
+		}
+		if dryRun {
+			data, _ := json.MarshalIndent(payload, "", "  ")
+			fmt.Println(string(data))
+		} else if err := postJSON(Config.Report.WebhookURL, payload); err != nil {
+			panic(err)
+		}
+	}
+	if Config.Report.Email.Host != "" {
+		msg, err := newEmailMessage(&Config.Report.Email, emailSections(sections), start)
+		if err != nil {
+			panic(err)
+		}
+		if dryRun {
+			fmt.Println(string(msg))
+		} else if err := sendEmail(&Config.Report.Email, msg); err != nil {
+			panic(err)
+		}
+	}
+	if dryRun {
+		return
+	}
+	fmt.Printf("report sent and spent %v minutes\n", time.Since(start).Minutes())
+}
+
+// reportSections returns metrics of this week beginning at weekFirstDay and of the stat period,
+// week is left out if it is disabled.
+func reportSections(all *AllPullRequestMetrics, fetchedAt time.Time, weekFirstDay time.Time) []*reportSection {
+	var sections []*reportSection
+	for _, window := range []string{WindowWeek, WindowOverall} {
+		if weekDisabled(window) {
+			continue
+		}
+		sections = append(sections, &reportSection{
+			Title:   windowTitle(window, fetchedAt, weekFirstDay),
+			Metrics: sortMetrics(merge(windowMetrics(all, window))),
+		})
+	}
+	return sections
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackPayload struct {
+	Text   string       `json:"text"` // shown in notifications
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type mattermostPayload struct {
+	Text string `json:"text"`
+}
+
+func newChatPayload(format string, sections []*reportSection) (interface{}, error) {
+	switch format {
+	case "", ReportFormatSlack:
+		return newSlackPayload(sections), nil
+	case ReportFormatMattermost:
+		return newMattermostPayload(sections), nil
+	}
+	return nil, fmt.Errorf("unknown report format : %s, must be %q or %q", format, ReportFormatSlack, ReportFormatMattermost)
+}
+
+// newSlackPayload renders every section as a header and the table in code blocks.
+func newSlackPayload(sections []*reportSection) *slackPayload {
+	payload := &slackPayload{Text: "github contrib stats"}
+	for _, section := range sections {
+		payload.Blocks = append(payload.Blocks, slackBlock{Type: "header", Text: &slackText{Type: "plain_text", Text: section.Title}})
+		var buf bytes.Buffer
+		writeMetricsTable(&buf, section.Metrics, nil)
+		// long tables are split by lines into several sections
+		var chunk string
+		for _, line := range strings.SplitAfter(buf.String(), "\n") {
+			if len(chunk)+len(line)+len("``````") > slackSectionLimit {
+				payload.Blocks = append(payload.Blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "```" + chunk + "```"}})
+				chunk = ""
+			}
+			chunk += line
+		}
+		if chunk != "" {
+			payload.Blocks = append(payload.Blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "```" + chunk + "```"}})
+		}
+	}
+	return payload
+}
+
+// newMattermostPayload renders every section as a heading and a markdown table.
+func newMattermostPayload(sections []*reportSection) *mattermostPayload {
+	var buf bytes.Buffer
+	for _, section := range sections {
+		fmt.Fprintf(&buf, "#### %s\n\n", section.Title)
+		writeMarkdownTable(&buf, section.Metrics)
+		buf.WriteString("\n")
+	}
+	return &mattermostPayload{Text: buf.String()}
+}
+
+// writeMarkdownTable writes metrics merged by user as a markdown table with a "Total" row.
+func writeMarkdownTable(buf *bytes.Buffer, metrics []*PullRequestMetrics) {
+	buf.WriteString("| " + strings.Join(metricsTableHeader, " | ") + " |\n")
+	buf.WriteString("|:---" + strings.Repeat("|---:", len(metricsTableHeader)-1) + "|\n")
+	row := func(name string, m *PullRequestMetrics) {
+		buf.WriteString("| " + strings.Join([]string{name, strconv.Itoa(m.Merged), strconv.Itoa(m.MergedCommits),
+			strconv.Itoa(m.LGTMed), strconv.Itoa(m.NonLGTMed), strconv.Itoa(m.Created), strconv.Itoa(m.ClosedUnmerged),
+			acceptanceRate(m.Merged, m.ClosedUnmerged)}, " | ") + " |\n")
+	}
+	for _, m := range metrics {
+		row(userDisplayName(m.User), m)
+	}
+	row("**Total**", total(metrics))
+}
+
+func postJSON(url string, payload interface{}) error {
+	if url == "" {
+		return fmt.Errorf("webhook url of report is not configured")
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("failed to post report : %s %s", resp.Status, body)
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+package githubstat
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OutputFormatText = "text"
+	OutputFormatHTML = "html"
+
+	htmlChartWidth  = 720
+	htmlChartHeight = 240
+	htmlChartMargin = 40
+)
+
+// colors of users' lines in trend charts, used in turn
+var htmlChartColors = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+type htmlCell struct {
+	Text  string
+	Links []htmlLink // PRs or commits counted in the number, empty for totals
+}
+
+type htmlLink struct {
+	Text  string // e.g. "kubernetes/kubernetes#5"
+	Title string
+	URL   string // empty if the host has no known web site
+}
+
+type htmlRow struct {
+	Name  string
+	Cells []htmlCell
+}
+
+type htmlTable struct {
+	Title  string
+	Header []string
+	Rows   []*htmlRow
+	Total  *htmlRow
+}
+
+type htmlBar struct {
+	Name          string
+	Merged        int
+	MergedCommits int
+	MergedWidth   float64 // percent of the largest number of all users
+	CommitsWidth  float64
+}
+
+type htmlLine struct {
+	Name   string
+	Color  string
+	Points string // "x,y x,y ..." of polyline
+}
+
+type htmlAxisLabel struct {
+	X, Y float64
+	Text string
+}
+
+type htmlChart struct {
+	Title         string
+	Width, Height int
+	Lines         []htmlLine
+	XLabels       []htmlAxisLabel
+	YLabels       []htmlAxisLabel
+}
+
+type htmlReport struct {
+	GeneratedAt time.Time
+	Tables      []*htmlTable
+	Bars        []*htmlBar
+	Charts      []*htmlChart
+}
+
+// WriteHTML writes a self-contained HTML page of metrics, with sortable tables of windows, bar charts of
+// merged PRs and commits by user, their weekly trends, and links from numbers to the PRs and commits counted in them.
+func (a *AllPullRequestMetrics) WriteHTML(w io.Writer) error {
+	return htmlReportTemplate.Execute(w, newHTMLReport(a, time.Now()))
+}
+
+func newHTMLReport(all *AllPullRequestMetrics, generatedAt time.Time) *htmlReport {
+	report := &htmlReport{GeneratedAt: generatedAt}
+	for _, window := range []string{WindowWeek, WindowOverall} {
+		if weekDisabled(window) {
+			continue
+		}
+		// goals are of the stat period, so only the overall table has their progress
+		var progresses []*GoalProgress
+		if window == WindowOverall {
+			progresses = goalProgresses(all.Overall, generatedAt)
+		}
+		report.Tables = append(report.Tables, newHTMLTable(window, windowMetrics(all, window), generatedAt, progresses))
+	}
+
+	overall := sortMetrics(merge(all.Overall))
+	var maxMerged, maxCommits int
+	for _, m := range overall {
+		if m.Merged > maxMerged {
+			maxMerged = m.Merged
+		}
+		if m.MergedCommits > maxCommits {
+			maxCommits = m.MergedCommits
+		}
+	}
+	for _, m := range overall {
+		report.Bars = append(report.Bars, &htmlBar{
+			Name:          userDisplayName(m.User),
+			Merged:        m.Merged,
+			MergedCommits: m.MergedCommits,
+			MergedWidth:   percentOf(m.Merged, maxMerged),
+			CommitsWidth:  percentOf(m.MergedCommits, maxCommits),
+		})
+	}
+
+	end := Config.StatEndTime
+	if end.IsZero() {
+		end = generatedAt
+	}
+	weeks := trendWeeks(Config.StatBeginTime, end)
+	report.Charts = []*htmlChart{
+		newHTMLChart("Merged PRs by Week", weeks, overall, func(t WeeklyTrend) map[string]int { return t.Merged }),
+		newHTMLChart("Merged Commits by Week", weeks, overall, func(t WeeklyTrend) map[string]int { return t.MergedCommits }),
+		newHTMLChart("Created PRs by Week", weeks, overall, func(t WeeklyTrend) map[string]int { return t.Created }),
+	}
+	return report
+}
+
+func percentOf(n int, max int) float64 {
+	if max <= 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(max)
+}
+
+// htmlColumns are metrics of table columns between "User Name" and "Acceptance Rate",
+// with links to the PRs or commits counted in them.
+var htmlColumns = []struct {
+	value func(*PullRequestMetrics) int
+	links func(*PullRequestMetrics) []htmlLink
+}{
+	{func(m *PullRequestMetrics) int { return m.Merged }, func(m *PullRequestMetrics) []htmlLink {
+		return pullRequestLinks(m.Details.Merged)
+	}},
+	{func(m *PullRequestMetrics) int { return m.MergedCommits }, func(m *PullRequestMetrics) []htmlLink {
+		var links []htmlLink
+		for _, c := range m.Details.MergedCommits {
+			sha := c.SHA
+			if len(sha) > 7 {
+				sha = sha[:7]
+			}
+			links = append(links, htmlLink{Text: c.Repo + "@" + sha, Title: c.Title, URL: c.URL})
+		}
+		return links
+	}},
+	{func(m *PullRequestMetrics) int { return m.LGTMed }, func(m *PullRequestMetrics) []htmlLink {
+		return pullRequestLinks(m.Details.LGTMed)
+	}},
+	{func(m *PullRequestMetrics) int { return m.NonLGTMed }, func(m *PullRequestMetrics) []htmlLink {
+		return pullRequestLinks(m.Details.NonLGTMed)
+	}},
+	{func(m *PullRequestMetrics) int { return m.Created }, func(m *PullRequestMetrics) []htmlLink {
+		return pullRequestLinks(m.Details.Created)
+	}},
+	{func(m *PullRequestMetrics) int { return m.ClosedUnmerged }, func(m *PullRequestMetrics) []htmlLink {
+		var links []htmlLink
+		for _, pr := range m.ClosedUnmergedPRs {
+			links = append(links, htmlLink{Text: fmt.Sprintf("%s#%d", pr.Repo, pr.Number), Title: pr.Title, URL: pr.URL})
+		}
+		return links
+	}},
+}
+
+func pullRequestLinks(prs []*PullRequestDetail) []htmlLink {
+	var links []htmlLink
+	for _, pr := range prs {
+		links = append(links, htmlLink{Text: fmt.Sprintf("%s#%d", pr.Repo, pr.Number), Title: pr.Title, URL: pr.URL})
+	}
+	return links
+}
+
+func newHTMLTable(window string, metrics []*PullRequestMetrics, fetchedAt time.Time, progresses []*GoalProgress) *htmlTable {
+	goalHeader, goalCells := goalColumns(progresses)
+	table := &htmlTable{Title: windowTitle(window, fetchedAt, Config.ThisWeekFirstDay),
+		Header: append(append([]string{}, metricsTableHeader...), goalHeader...)}
+	for _, m := range sortMetrics(merge(metrics)) {
+		row := &htmlRow{Name: userDisplayName(m.User)}
+		for _, column := range htmlColumns {
+			row.Cells = append(row.Cells, htmlCell{Text: strconv.Itoa(column.value(m)), Links: column.links(m)})
+		}
+		row.Cells = append(row.Cells, htmlCell{Text: acceptanceRate(m.Merged, m.ClosedUnmerged)})
+		for _, cell := range goalCellsOf(goalHeader, goalCells, m.User) {
+			row.Cells = append(row.Cells, htmlCell{Text: cell})
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	sum := total(metrics)
+	table.Total = &htmlRow{Name: "Total"}
+	for _, column := range htmlColumns {
+		table.Total.Cells = append(table.Total.Cells, htmlCell{Text: strconv.Itoa(column.value(sum))})
+	}
+	table.Total.Cells = append(table.Total.Cells, htmlCell{Text: acceptanceRate(sum.Merged, sum.ClosedUnmerged)})
+	for range goalHeader {
+		table.Total.Cells = append(table.Total.Cells, htmlCell{})
+	}
+	return table
+}
+
+// webURLOf returns the web url of a GitHub host, empty if the host is not GitHub.
+func webURLOf(hostName string) string {
+	if hostName == "" || hostName == DefaultHost {
+		return "https://github.com"
+	}
+	host := findHost(hostName)
+	if host.Type != "" && host.Type != HostTypeGitHub {
+		return ""
+	}
+	if host.BaseURL != "" {
+		if u, err := url.Parse(host.BaseURL); err == nil && u.Host != "" {
+			return u.Scheme + "://" + u.Host
+		}
+	}
+	return "https://" + host.Name
+}
+
+// newHTMLChart draws a line of counts by week for every user.
+func newHTMLChart(title string, weeks []string, metrics []*PullRequestMetrics, counts func(WeeklyTrend) map[string]int) *htmlChart {
+	chart := &htmlChart{Title: title, Width: htmlChartWidth, Height: htmlChartHeight}
+	max := 1
+	for _, m := range metrics {
+		for _, week := range weeks {
+			if n := counts(m.Trend)[week]; n > max {
+				max = n
+			}
+		}
+	}
+	plotWidth := float64(htmlChartWidth - 2*htmlChartMargin)
+	plotHeight := float64(htmlChartHeight - 2*htmlChartMargin)
+	x := func(i int) float64 {
+		if len(weeks) <= 1 {
+			return htmlChartMargin
+		}
+		return htmlChartMargin + plotWidth*float64(i)/float64(len(weeks)-1)
+	}
+	y := func(n int) float64 {
+		return htmlChartMargin + plotHeight*float64(max-n)/float64(max)
+	}
+	for i, m := range metrics {
+		var points []string
+		for j, week := range weeks {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(j), y(counts(m.Trend)[week])))
+		}
+		chart.Lines = append(chart.Lines, htmlLine{
+			Name:   userDisplayName(m.User),
+			Color:  htmlChartColors[i%len(htmlChartColors)],
+			Points: strings.Join(points, " "),
+		})
+	}
+	// at most about 8 labels of weeks
+	step := (len(weeks) + 7) / 8
+	for i := 0; i < len(weeks); i += step {
+		chart.XLabels = append(chart.XLabels, htmlAxisLabel{X: x(i), Y: float64(htmlChartHeight - htmlChartMargin + 16), Text: weeks[i]})
+	}
+	for _, n := range []int{0, max / 2, max} {
+		chart.YLabels = append(chart.YLabels, htmlAxisLabel{X: htmlChartMargin - 6, Y: y(n) + 4, Text: strconv.Itoa(n)})
+	}
+	return chart
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>github contrib stats</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+td.number, tfoot th.number { text-align: right; }
+thead th { cursor: pointer; background: #f4f4f4; }
+thead th.asc::after { content: " \25B2"; }
+thead th.desc::after { content: " \25BC"; }
+a { color: inherit; }
+details { text-align: left; white-space: nowrap; }
+summary { text-align: right; cursor: pointer; }
+.bars td.bar { width: 320px; }
+.bar div { height: 12px; margin: 2px 0; }
+.merged { background: #4e79a7; }
+.commits { background: #f28e2b; }
+.legend span { display: inline-block; margin-right: 1em; }
+.legend i { display: inline-block; width: 12px; height: 12px; margin-right: 4px; }
+</style>
+</head>
+<body>
+<h1>github contrib stats</h1>
+<p>generated at {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+{{range .Tables}}
+<h2>{{.Title}}</h2>
+<table class="sortable">
+<thead><tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr><td>{{.Name}}</td>{{range .Cells}}<td class="number">{{if .Links}}<details><summary>{{.Text}}</summary>
+{{range .Links}}{{if .URL}}<a href="{{.URL}}" title="{{.Title}}">{{.Text}}</a>{{else}}<span title="{{.Title}}">{{.Text}}</span>{{end}}<br>
+{{end}}</details>{{else}}{{.Text}}{{end}}</td>{{end}}</tr>
+{{end}}</tbody>
+{{with .Total}}<tfoot><tr><th>{{.Name}}</th>{{range .Cells}}<th class="number">{{.Text}}</th>{{end}}</tr></tfoot>{{end}}
+</table>
+{{end}}
+<h2>Merged PRs and Commits by User</h2>
+<p class="legend"><span><i class="merged"></i>Merged PRs</span><span><i class="commits"></i>Merged Commits</span></p>
+<table class="bars">
+{{range .Bars}}<tr><td>{{.Name}}</td><td class="bar"><div class="merged" style="width: {{printf "%.1f" .MergedWidth}}%"></div><div class="commits" style="width: {{printf "%.1f" .CommitsWidth}}%"></div></td>
+<td class="number">{{.Merged}}<br>{{.MergedCommits}}</td></tr>
+{{end}}</table>
+{{range .Charts}}
+<h2>{{.Title}}</h2>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" font-size="11">
+{{range .YLabels}}<text x="{{.X}}" y="{{.Y}}" text-anchor="end">{{.Text}}</text>
+{{end}}{{range .XLabels}}<text x="{{.X}}" y="{{.Y}}" text-anchor="middle">{{.Text}}</text>
+{{end}}{{range .Lines}}<polyline fill="none" stroke="{{.Color}}" stroke-width="2" points="{{.Points}}"><title>{{.Name}}</title></polyline>
+{{end}}</svg>
+<p class="legend">{{range .Lines}}<span><i style="background: {{.Color}}"></i>{{.Name}}</span>{{end}}</p>
+{{end}}
+<script>
+// sorts rows of a table by the clicked column, the Total row in tfoot stays at the bottom,
+// numbers with links are sorted by their summaries
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("thead th").forEach(function (th, column) {
+    th.addEventListener("click", function () {
+      var desc = !th.classList.contains("desc");
+      table.querySelectorAll("thead th").forEach(function (other) { other.classList.remove("asc", "desc"); });
+      th.classList.add(desc ? "desc" : "asc");
+      var tbody = table.tBodies[0];
+      var rows = Array.prototype.slice.call(tbody.rows);
+      var key = function (row) {
+        var cell = row.cells[column];
+        var text = (cell.querySelector("summary") || cell).textContent.trim();
+        var n = parseFloat(text);
+        return isNaN(n) ? text.toLowerCase() : n;
+      };
+      rows.sort(function (a, b) {
+        var x = key(a), y = key(b);
+        if (typeof x !== typeof y) {
+          x = String(x);
+          y = String(y);
+        }
+        var order = x < y ? -1 : x > y ? 1 : 0;
+        return desc ? -order : order;
+      });
+      rows.forEach(function (row) { tbody.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,70 @@
+package githubstat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_newHTMLReport(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "", "2016-12-24T00:00:00Z")()
+	users := Config.Users
+	defer func() { Config.Users = users }()
+	Config.Users = []User{{Name: "bruceauyeung", RealName: "欧阳钦华"}, {Name: "tanshanshan"}}
+
+	all := newTestServer().fetch(nil, Config.ThisWeekFirstDay)
+	all.Overall[0].Trend.addMerged(fakeTime("2016-10-10T00:00:00Z"))
+	all.Overall[2].Trend.addMerged(fakeTime("2016-12-25T00:00:00Z"))
+	all.Overall[0].Details.Merged[0].Title = "fix typo"
+	all.Overall[0].Details.Merged[0].URL = "https://github.com/kubernetes/kubernetes/pull/5"
+	all.Overall[0].Details.MergedCommits = []*CommitDetail{{User: "bruceauyeung", Repo: "kubernetes/kubernetes",
+		SHA: "aaaaaaaaaa", URL: "https://github.com/kubernetes/kubernetes/commit/aaaaaaaaaa"}}
+	all.Overall[1].ClosedUnmerged = 1
+	all.Overall[1].ClosedUnmergedPRs = []*ClosedPullRequest{{User: "tanshanshan", Repo: "kubernetes/kubernetes", Number: 3}}
+	report := newHTMLReport(all, *fakeTime("2016-12-26T00:00:00Z"))
+
+	if len(report.Tables) != 2 || !strings.HasPrefix(report.Tables[0].Title, "Statistics for this Week") {
+		t.Fatalf("tables are %+v, want week and overall", report.Tables)
+	}
+	overall := report.Tables[1]
+	if len(overall.Rows) != 2 || overall.Rows[0].Name != "bruceauyeung(欧阳钦华)" || overall.Rows[0].Cells[0].Text != "5" ||
+		overall.Total.Cells[0].Text != "6" || len(overall.Total.Cells[0].Links) != 0 {
+		t.Errorf("overall table is %+v", overall)
+	}
+	// numbers link to the PRs and commits counted in them
+	want := htmlLink{Text: "kubernetes/kubernetes#5", Title: "fix typo", URL: "https://github.com/kubernetes/kubernetes/pull/5"}
+	if links := overall.Rows[0].Cells[0].Links; len(links) != 1 || links[0] != want {
+		t.Errorf("links of merged PRs of bruceauyeung are %+v, want %+v", links, want)
+	}
+	if links := overall.Rows[0].Cells[1].Links; len(links) != 1 || links[0].Text != "kubernetes/kubernetes@aaaaaaa" ||
+		links[0].URL != "https://github.com/kubernetes/kubernetes/commit/aaaaaaaaaa" {
+		t.Errorf("links of merged commits of bruceauyeung are %+v", links)
+	}
+	if links := overall.Rows[1].Cells[5].Links; len(links) != 1 || links[0].Text != "kubernetes/kubernetes#3" {
+		t.Errorf("links of closed unmerged PRs of tanshanshan are %+v", links)
+	}
+	// numbers without details are not linked
+	if cell := report.Tables[0].Rows[0].Cells[0]; cell.Text != "1" || len(cell.Links) != 0 {
+		t.Errorf("merged PRs of bruceauyeung this week is %+v", cell)
+	}
+
+	if len(report.Bars) != 2 || report.Bars[0].MergedWidth != 100 || report.Bars[1].MergedWidth != 20 {
+		t.Errorf("bars are %+v", report.Bars)
+	}
+	chart := report.Charts[0]
+	weeks := trendWeeks(Config.StatBeginTime, *fakeTime("2016-12-26T00:00:00Z"))
+	if len(chart.Lines) != 2 || len(strings.Fields(chart.Lines[0].Points)) != len(weeks) || len(weeks) < 12 || len(chart.XLabels) == 0 {
+		t.Errorf("trend chart is %+v", chart)
+	}
+
+	var buf bytes.Buffer
+	if err := htmlReportTemplate.Execute(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`<table class="sortable">`, "<polyline", "<th>Total</th>", "<summary>5</summary>",
+		`<a href="https://github.com/kubernetes/kubernetes/pull/5" title="fix typo">kubernetes/kubernetes#5</a>`} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("html report does not contain %q", s)
+		}
+	}
+}
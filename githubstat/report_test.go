@@ -0,0 +1,75 @@
+package githubstat
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_chatPayload(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "", "2016-12-24T00:00:00Z")()
+	// this week of the report is not the configured one
+	weekFirstDay := *fakeTime("2016-12-19T00:00:00Z")
+	sections := reportSections(newTestServer().fetch(nil, weekFirstDay), *fakeTime("2016-12-25T00:00:00Z"), weekFirstDay)
+	if len(sections) != 2 || sections[0].Title != "Statistics for this Week ( week first day : 2016-12-19)" {
+		t.Fatalf("sections are %+v, want week and overall", sections)
+	}
+
+	slack := newSlackPayload(sections)
+	if len(slack.Blocks) != 4 || slack.Blocks[0].Type != "header" || slack.Blocks[3].Type != "section" ||
+		!strings.HasPrefix(slack.Blocks[3].Text.Text, "```") || !strings.Contains(slack.Blocks[3].Text.Text, " bruceauyeung ") {
+		t.Errorf("slack payload is %+v", slack)
+	}
+	mattermost := newMattermostPayload(sections)
+	for _, want := range []string{
+		"#### Overall Statistics ( 2016-10-01 ~ 2016-12-25)\n",
+		"| bruceauyeung | 5 | 6 | 0 | 0 | 0 | 0 | 100.0% |\n",
+		"| **Total** | 6 | 7 | 0 | 0 | 0 | 0 | 100.0% |\n",
+	} {
+		if !strings.Contains(mattermost.Text, want) {
+			t.Errorf("mattermost payload does not contain %q:\n%s", want, mattermost.Text)
+		}
+	}
+	if _, err := newChatPayload("irc", sections); err == nil {
+		t.Errorf("unknown format should fail")
+	}
+
+	// long tables are split into sections within the limit
+	var many []*PullRequestMetrics
+	for i := 0; i < 100; i++ {
+		many = append(many, &PullRequestMetrics{User: strings.Repeat("u", 10) + string(rune('a'+i%26)) + string(rune('a'+i/26))})
+	}
+	slack = newSlackPayload([]*reportSection{{Title: "many", Metrics: many}})
+	if len(slack.Blocks) < 3 {
+		t.Errorf("long table is not split, got %d blocks", len(slack.Blocks))
+	}
+	for _, block := range slack.Blocks {
+		if len(block.Text.Text) > slackSectionLimit {
+			t.Errorf("block of %d characters exceeds the limit", len(block.Text.Text))
+		}
+	}
+}
+
+func Test_postJSON(t *testing.T) {
+	var received mattermostPayload
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil || received.Text == "fail" {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+		}
+	}))
+	defer s.Close()
+
+	if err := postJSON(s.URL, &mattermostPayload{Text: "hello"}); err != nil || received.Text != "hello" {
+		t.Errorf("posted %+v, error %v", received, err)
+	}
+	if err := postJSON(s.URL, &mattermostPayload{Text: "fail"}); err == nil {
+		t.Errorf("posting should fail on bad request")
+	}
+	if err := postJSON("", &mattermostPayload{}); err == nil {
+		t.Errorf("posting without url should fail")
+	}
+}
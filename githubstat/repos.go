@@ -0,0 +1,114 @@
+package githubstat
+
+import (
+	"path"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// RepoFilter filters repositories listed for glob patterns, repositories specified by name are never filtered.
+type RepoFilter struct {
+	IncludeForks    bool
+	IncludeArchived bool
+	ExcludePrivate  bool
+	Topics          []string // repositories with any of these topics, empty means all
+	Languages       []string // repositories in any of these languages, empty means all
+}
+
+func (f *RepoFilter) match(r *github.Repository) bool {
+	if !f.IncludeForks && r.Fork != nil && *r.Fork {
+		return false
+	}
+	if !f.IncludeArchived && r.Archived != nil && *r.Archived {
+		return false
+	}
+	if f.ExcludePrivate && r.Private != nil && *r.Private {
+		return false
+	}
+	if len(f.Topics) != 0 && !StringSliceContainsAnyFold(r.Topics, f.Topics...) {
+		return false
+	}
+	if len(f.Languages) != 0 && (r.Language == nil || !StringSliceContainsAnyFold(f.Languages, *r.Language)) {
+		return false
+	}
+	return true
+}
+
+// isRepoPattern tells whether repo name is a glob pattern, e.g. "*" or "kube-*".
+func isRepoPattern(repoName string) bool {
+	return strings.ContainsAny(repoName, "*?[")
+}
+
+// matchRepo tells whether repo matches pattern, whose owner name and repo name may be glob patterns.
+func matchRepo(pattern *RepoParameters, repo *RepoParameters) bool {
+	if pattern.Host != repo.Host {
+		return false
+	}
+	if matched, _ := path.Match(*pattern.OwnerName, *repo.OwnerName); !matched {
+		return false
+	}
+	matched, _ := path.Match(*pattern.RepoName, *repo.RepoName)
+	return matched
+}
+
+// expandRepos resolves glob patterns into repositories listed from providers, and removes excluded repositories.
+func (m *PullRequestMetricsRequest) expandRepos() {
+	var expanded []*RepoParameters
+	var excludes []*RepoParameters
+	found := make(map[string]bool)
+	add := func(repo *RepoParameters) {
+		if !found[repo.String()] {
+			found[repo.String()] = true
+			expanded = append(expanded, repo)
+		}
+	}
+	// repositories of owners, which are listed only once
+	listed := make(map[string][]*github.Repository)
+	for _, repo := range m.param.Repos {
+		ownerName := *repo.OwnerName
+		repoName := *repo.RepoName
+		if repo.Exclude {
+			excludes = append(excludes, repo)
+		} else if isRepoPattern(repoName) {
+			key := repo.Host + ":" + ownerName
+			repos, ok := listed[key]
+			if !ok {
+				var err error
+				if repos, err = m.providerOf(repo.Host).ListRepositories(ownerName); err != nil {
+					panic(err)
+				}
+				listed[key] = repos
+			}
+			for _, r := range repos {
+				if matched, _ := path.Match(repoName, *r.Name); matched && Config.RepoFilter.match(r) {
+					add(&RepoParameters{OwnerName: r.Owner.Login, RepoName: r.Name, Host: repo.Host})
+				}
+			}
+
+		} else {
+			add(repo)
+		}
+	}
+
+	m.param.Repos = nil
+	for _, repo := range expanded {
+		excluded := false
+		for _, exclude := range excludes {
+			if matchRepo(exclude, repo) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			m.param.Repos = append(m.param.Repos, repo)
+		}
+	}
+}
+
+// ResolveRepos resolves glob patterns and exclusions of repos into the repositories that metrics are fetched from.
+func ResolveRepos(repos []*RepoParameters) []*RepoParameters {
+	m := &PullRequestMetricsRequest{param: &MetricsParameters{Repos: repos}}
+	m.expandRepos()
+	return m.param.Repos
+}
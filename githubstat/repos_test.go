@@ -0,0 +1,75 @@
+package githubstat
+
+import (
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+// repoListProvider lists the given repositories, other methods of Provider are not implemented.
+type repoListProvider struct {
+	Provider
+	repos []*github.Repository
+}
+
+func (p *repoListProvider) ListRepositories(owner string) ([]*github.Repository, error) {
+	return p.repos, nil
+}
+
+func newTestRepository(owner string, name string, fork bool, archived bool, topics ...string) *github.Repository {
+	return &github.Repository{Owner: &github.User{Login: &owner}, Name: &name, Fork: &fork, Archived: &archived, Topics: topics}
+}
+
+func Test_expandRepos(t *testing.T) {
+	filter := Config.RepoFilter
+	defer func() { Config.RepoFilter = filter }()
+	provider := &repoListProvider{repos: []*github.Repository{
+		newTestRepository("kubernetes", "kubernetes", false, false, "sig-node"),
+		newTestRepository("kubernetes", "kube-state-metrics", false, false),
+		newTestRepository("kubernetes", "kube-deploy", false, true),
+		newTestRepository("kubernetes", "kube-fork", true, false),
+		newTestRepository("kubernetes", "website", false, false, "sig-docs"),
+	}}
+	cases := []struct {
+		repos  []string
+		filter RepoFilter
+		want   []string
+	}{
+		{[]string{"kubernetes/*"}, RepoFilter{}, []string{"kubernetes/kubernetes", "kubernetes/kube-state-metrics", "kubernetes/website"}},
+		{[]string{"kubernetes/kube-*"}, RepoFilter{}, []string{"kubernetes/kube-state-metrics"}},
+		{[]string{"kubernetes/kube-*"}, RepoFilter{IncludeForks: true, IncludeArchived: true},
+			[]string{"kubernetes/kube-state-metrics", "kubernetes/kube-deploy", "kubernetes/kube-fork"}},
+		{[]string{"kubernetes/*", "!kubernetes/website", "!kubernetes/kube-*"}, RepoFilter{}, []string{"kubernetes/kubernetes"}},
+		{[]string{"kubernetes/*"}, RepoFilter{Topics: []string{"SIG-docs"}}, []string{"kubernetes/website"}},
+		// repos specified by name are never filtered
+		{[]string{"kubernetes/kube-deploy", "kubernetes/*"}, RepoFilter{Topics: []string{"sig-node"}},
+			[]string{"kubernetes/kube-deploy", "kubernetes/kubernetes"}},
+	}
+	for _, c := range cases {
+		var repos []*RepoParameters
+		for _, r := range c.repos {
+			repo, err := ParseRepo(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			repos = append(repos, repo)
+		}
+		Config.RepoFilter = c.filter
+		m := &PullRequestMetricsRequest{param: &MetricsParameters{Repos: repos}, providers: map[string]Provider{"": provider}}
+		m.expandRepos()
+		var got []string
+		for _, repo := range m.param.Repos {
+			got = append(got, repo.String())
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("repos %v with filter %+v are resolved into %v, want %v", c.repos, c.filter, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("repos %v with filter %+v are resolved into %v, want %v", c.repos, c.filter, got, c.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package githubstat
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	DefaultListen         = ":8080"
+	DefaultRefreshMinutes = 60
+
+	WindowOverall = "overall"
+	WindowWeek    = "week"
+)
+
+type ServerConfig struct {
+	Listen         string // address the server listens on, e.g. ":8080"
+	RefreshMinutes int    // metrics are fetched again every refreshMinutes
+	WebhookSecret  string // secret of webhook events, webhook is disabled if it is empty
+}
+
+// Server fetches metrics of repos on schedule, and serves the latest metrics as a dashboard and JSON api.
+type Server struct {
+	repos []*RepoParameters
+	// fetch fetches metrics of all users by repo with this week beginning at weekFirstDay,
+	// and rateLimits gets API quotas by host, which are replaced by tests
+	fetch      func(repos []*RepoParameters, weekFirstDay time.Time) *AllPullRequestMetrics
+	rateLimits func(repos []*RepoParameters) map[string]*github.RateLimits
+
+	mu            sync.RWMutex
+	metrics       *AllPullRequestMetrics
+	weekFirstDay  time.Time // this week of metrics, which moves on while the server is running
+	quotas        map[string]*github.RateLimits
+	refreshedAt   time.Time
+	fetchDuration time.Duration
+
+	// store updated by webhook events, nil if webhook is disabled
+	storeMu sync.Mutex
+	store   *Store
+	// eventProvider creates a provider fetching what a webhook event refers to by API, which is replaced by tests.
+	// every event has a fresh provider, so that nothing cached by providers goes stale.
+	eventProvider func(host string) Provider
+}
+
+func NewServer(repos []*RepoParameters) *Server {
+	s := &Server{repos: repos, fetch: fetchPullRequestMetrics, rateLimits: getRateLimits,
+		eventProvider: func(host string) Provider { return newProvider(getProxyClient(host), "", statPeriod{}) }}
+	if Config.Server.WebhookSecret != "" {
+		store, err := loadStore(Config.StorePath)
+		if err != nil {
+			panic(err)
+		}
+		s.store = store
+	}
+	return s
+}
+
+func fetchPullRequestMetrics(repos []*RepoParameters, weekFirstDay time.Time) *AllPullRequestMetrics {
+	// patterns of repos are resolved again by every fetch, so new repos are picked up
+	m := &PullRequestMetricsRequest{weekFirstDay: weekFirstDay}
+	m.SetParameters(&MetricsParameters{Repos: append([]*RepoParameters{}, repos...)})
+	return m.FetchMetrics().(*AllPullRequestMetrics)
+}
+
+// Serve refreshes metrics every Config.Server.RefreshMinutes in background, and serves them on Config.Server.Listen.
+func (s *Server) Serve() error {
+	go func() {
+		for {
+			s.refresh()
+			time.Sleep(time.Duration(Config.Server.RefreshMinutes) * time.Minute)
+		}
+	}()
+	fmt.Printf("serving on %s\n", Config.Server.Listen)
+	return http.ListenAndServe(Config.Server.Listen, s.Handler())
+}
+
+// refresh fetches metrics again, the latest metrics are kept if fetching fails.
+func (s *Server) refresh() {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("failed to refresh metrics : %v\n", r)
+		}
+	}()
+	s.flushStore()
+	start := time.Now()
+	weekFirstDay := getWeekFirstDay(start)
+	metrics := s.fetch(s.repos, weekFirstDay)
+	quotas := s.rateLimits(s.repos)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = metrics
+	s.weekFirstDay = weekFirstDay
+	s.quotas = quotas
+	s.refreshedAt = time.Now()
+	s.fetchDuration = s.refreshedAt.Sub(start)
+	fmt.Printf("metrics refreshed and spent %v minutes\n", s.fetchDuration.Minutes())
+}
+
+// flushStore saves changes of the store made by webhook events since the last checkpoint.
+func (s *Server) flushStore() {
+	if s.store == nil {
+		return
+	}
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	if err := s.store.flush(); err != nil {
+		fmt.Printf("failed to save store : %v\n", err)
+	}
+}
+
+// latest returns the latest metrics, nil if metrics are never fetched.
+func (s *Server) latest() (*AllPullRequestMetrics, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.metrics, s.refreshedAt
+}
+
+// thisWeek returns the first day of this week of the latest metrics.
+func (s *Server) thisWeek() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.weekFirstDay
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveDashboard)
+	mux.HandleFunc("/api/overall", s.serveWindow(WindowOverall))
+	mux.HandleFunc("/api/week", s.serveWindow(WindowWeek))
+	mux.HandleFunc("/api/users/", s.serveUser)
+	mux.HandleFunc("/badge/", s.serveBadge)
+	mux.HandleFunc("/card/", s.serveCard)
+	mux.HandleFunc("/metrics", s.serveMetrics)
+	mux.HandleFunc("/webhook", s.serveWebhook)
+	return mux
+}
+
+// metricsQuery is parsed from query parameters "window", "repos", "users" and "details",
+// repos and users are separated by commas, and repos may be glob patterns.
+type metricsQuery struct {
+	windows []string
+	repos   []*RepoParameters
+	users   []string
+	details bool // whether PRs and commits behind metrics are included
+}
+
+func parseMetricsQuery(r *http.Request) (*metricsQuery, error) {
+	query := r.URL.Query()
+	q := &metricsQuery{windows: []string{WindowOverall, WindowWeek}}
+	switch window := query.Get("window"); window {
+	case "":
+	case WindowOverall, WindowWeek:
+		q.windows = []string{window}
+	default:
+		return nil, fmt.Errorf("unknown window : %s, must be %q or %q", window, WindowOverall, WindowWeek)
+	}
+	for _, repoStr := range splitQuery(query.Get("repos")) {
+		repo, err := ParseRepo(repoStr)
+		if err != nil {
+			return nil, err
+		}
+		q.repos = append(q.repos, repo)
+	}
+	q.users = splitQuery(query.Get("users"))
+	q.details = query.Get("details") == "true"
+	return q, nil
+}
+
+func splitQuery(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// filter returns metrics of the queried repos and users, metrics are left untouched.
+func (q *metricsQuery) filter(metrics []*PullRequestMetrics) []*PullRequestMetrics {
+	var filtered []*PullRequestMetrics
+	for _, m := range metrics {
+		if len(q.users) != 0 && !StringSliceContainsAnyFold(q.users, m.User) {
+			continue
+		}
+		if len(q.repos) != 0 {
+			repo, err := ParseRepo(m.Repo)
+			if err != nil {
+				continue
+			}
+			matched := false
+			for _, pattern := range q.repos {
+				if matchRepo(pattern, repo) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		filtered = append(filtered, m)
+	}
+	return filtered
+}
+
+// windowMetrics returns metrics of the window by repo and user.
+func windowMetrics(all *AllPullRequestMetrics, window string) []*PullRequestMetrics {
+	if window == WindowWeek {
+		return all.Week
+	}
+	return all.Overall
+}
+
+// total sums up metrics of all users and repos.
+func total(metrics []*PullRequestMetrics) *PullRequestMetrics {
+	merged := mergeBy(metrics, func(*PullRequestMetrics) string { return "" })
+	if len(merged) == 0 {
+		return &PullRequestMetrics{User: "Total"}
+	}
+	merged[0].User = "Total"
+	merged[0].Repo = ""
+	return merged[0]
+}
+
+// windowTitle returns title of metrics of the window fetched at the given time, with this week beginning
+// at weekFirstDay.
+func windowTitle(window string, fetchedAt time.Time, weekFirstDay time.Time) string {
+	if window == WindowWeek {
+		return fmt.Sprintf("Statistics for this Week ( week first day : %s)", weekFirstDay.Format("2006-01-02"))
+	}
+	endTime := Config.StatEndTime
+	if endTime.IsZero() {
+		endTime = fetchedAt
+	}
+	return fmt.Sprintf("Overall Statistics ( %s ~ %s)", Config.StatBeginTime.Format("2006-01-02"), endTime.Format("2006-01-02"))
+}
+
+type windowResponse struct {
+	Window        string
+	RefreshedAt   time.Time
+	StatBeginTime time.Time
+	StatEndTime   time.Time
+	Metrics       []*PullRequestMetrics // by user
+	Total         *PullRequestMetrics
+}
+
+type userResponse struct {
+	User        string
+	RealName    string
+	RefreshedAt time.Time
+	// metrics of the user by window, and by repo of every window
+	Windows map[string]*PullRequestMetrics
+	Repos   map[string][]*PullRequestMetrics
+}
+
+// weekDisabled tells whether week statistics is disabled, see WeekPullRequestMetrics.Show.
+func weekDisabled(window string) bool {
+	return window == WindowWeek && !Config.StatEndTime.IsZero()
+}
+
+func (s *Server) serveWindow(window string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		all, refreshedAt := s.latest()
+		if all == nil {
+			http.Error(w, "metrics are not fetched yet", http.StatusServiceUnavailable)
+			return
+		}
+		if weekDisabled(window) {
+			http.Error(w, "week statistics is disabled because statEndTime is specified", http.StatusNotFound)
+			return
+		}
+		q, err := parseMetricsQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filtered := q.filter(windowMetrics(all, window))
+		resp := &windowResponse{
+			Window:        window,
+			RefreshedAt:   refreshedAt,
+			StatBeginTime: Config.StatBeginTime,
+			StatEndTime:   Config.StatEndTime,
+			Metrics:       sortMetrics(merge(filtered)),
+			Total:         total(filtered),
+		}
+		// details of all users are large, they are drilled down by user unless asked for
+		if !q.details {
+			for _, m := range append(resp.Metrics, resp.Total) {
+				m.Details = MetricsDetails{}
+			}
+		}
+		writeJSON(w, resp)
+	}
+}
+
+func (s *Server) serveUser(w http.ResponseWriter, r *http.Request) {
+	all, refreshedAt := s.latest()
+	if all == nil {
+		http.Error(w, "metrics are not fetched yet", http.StatusServiceUnavailable)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/api/users/")
+	var user *User
+	for i := range Config.Users {
+		if Config.Users[i].Name == name {
+			user = &Config.Users[i]
+		}
+	}
+	if user == nil {
+		http.Error(w, fmt.Sprintf("user %s is not configured", name), http.StatusNotFound)
+		return
+	}
+	q, err := parseMetricsQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.users = []string{user.Name}
+	resp := &userResponse{
+		User:        user.Name,
+		RealName:    user.RealName,
+		RefreshedAt: refreshedAt,
+		Windows:     make(map[string]*PullRequestMetrics),
+		Repos:       make(map[string][]*PullRequestMetrics),
+	}
+	for _, window := range q.windows {
+		if weekDisabled(window) {
+			continue
+		}
+		filtered := q.filter(windowMetrics(all, window))
+		resp.Windows[window] = total(filtered)
+		resp.Windows[window].User = user.Name
+		resp.Repos[window] = filtered
+	}
+	writeJSON(w, resp)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("failed to write response : %v\n", err)
+	}
+}
+
+type dashboardTable struct {
+	Title   string
+	Metrics []*PullRequestMetrics
+	Total   *PullRequestMetrics
+}
+
+type dashboard struct {
+	RefreshedAt time.Time
+	Repos       string
+	Users       string
+	Tables      []*dashboardTable
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"displayName":    userDisplayName,
+	"acceptanceRate": acceptanceRate,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="300">
+<title>github contrib stats</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+tr.total { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>github contrib stats</h1>
+<p>refreshed at {{.RefreshedAt.Format "2006-01-02 15:04:05"}}</p>
+<form method="get">
+repos <input name="repos" value="{{.Repos}}" placeholder="kubernetes/kube-*">
+users <input name="users" value="{{.Users}}" placeholder="bruceauyeung">
+<input type="submit" value="filter">
+</form>
+{{range .Tables}}
+<h2>{{.Title}}</h2>
+<table>
+<tr><th>User Name</th><th>Merged PRs</th><th>Merged Commits</th><th>LGTM'ed PRs</th><th>NonLGTM'ed PRs</th><th>Created PRs</th><th>Closed Unmerged PRs</th><th>Acceptance Rate</th></tr>
+{{range .Metrics}}<tr><td><a href="/api/users/{{.User}}">{{displayName .User}}</a></td><td>{{.Merged}}</td><td>{{.MergedCommits}}</td><td>{{.LGTMed}}</td><td>{{.NonLGTMed}}</td><td>{{.Created}}</td><td>{{.ClosedUnmerged}}</td><td>{{acceptanceRate .Merged .ClosedUnmerged}}</td></tr>
+{{end}}{{with .Total}}<tr class="total"><td>Total</td><td>{{.Merged}}</td><td>{{.MergedCommits}}</td><td>{{.LGTMed}}</td><td>{{.NonLGTMed}}</td><td>{{.Created}}</td><td>{{.ClosedUnmerged}}</td><td>{{acceptanceRate .Merged .ClosedUnmerged}}</td></tr>{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+func (s *Server) serveDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	all, refreshedAt := s.latest()
+	if all == nil {
+		http.Error(w, "metrics are not fetched yet, please refresh later", http.StatusServiceUnavailable)
+		return
+	}
+	q, err := parseMetricsQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d := &dashboard{
+		RefreshedAt: refreshedAt,
+		Repos:       r.URL.Query().Get("repos"),
+		Users:       r.URL.Query().Get("users"),
+	}
+	for _, window := range q.windows {
+		if weekDisabled(window) {
+			continue
+		}
+		filtered := q.filter(windowMetrics(all, window))
+		d.Tables = append(d.Tables, &dashboardTable{Title: windowTitle(window, refreshedAt, s.thisWeek()),
+			Metrics: sortMetrics(merge(filtered)), Total: total(filtered)})
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, d); err != nil {
+		fmt.Printf("failed to render dashboard : %v\n", err)
+	}
+}
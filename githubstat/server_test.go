@@ -0,0 +1,157 @@
+package githubstat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func newTestServer() *Server {
+	s := NewServer(nil)
+	s.rateLimits = func(repos []*RepoParameters) map[string]*github.RateLimits { return nil }
+	s.fetch = func(repos []*RepoParameters, weekFirstDay time.Time) *AllPullRequestMetrics {
+		return &AllPullRequestMetrics{
+			OverallPullRequestMetrics: &OverallPullRequestMetrics{Overall: []*PullRequestMetrics{
+				{User: "bruceauyeung", Repo: "kubernetes/kubernetes", Merged: 3, MergedCommits: 4,
+					Details: MetricsDetails{Merged: []*PullRequestDetail{{User: "bruceauyeung", Repo: "kubernetes/kubernetes", Number: 5}}}},
+				{User: "tanshanshan", Repo: "kubernetes/kubernetes", Merged: 1, MergedCommits: 1},
+				{User: "bruceauyeung", Repo: "kubernetes/website", Merged: 2, MergedCommits: 2},
+			}},
+			WeekPullRequestMetrics: &WeekPullRequestMetrics{Week: []*PullRequestMetrics{
+				{User: "bruceauyeung", Repo: "kubernetes/kubernetes", Merged: 1, MergedCommits: 1},
+				{User: "tanshanshan", Repo: "kubernetes/kubernetes"},
+				{User: "bruceauyeung", Repo: "kubernetes/website"},
+			}},
+		}
+	}
+	return s
+}
+
+func get(t *testing.T, handler http.Handler, url string, v interface{}) int {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+	if w.Code == http.StatusOK && v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("invalid response of %s : %v", url, err)
+		}
+	}
+	return w.Code
+}
+
+func Test_Server(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "", "2016-12-24T00:00:00Z")()
+	users := Config.Users
+	defer func() { Config.Users = users }()
+	Config.Users = []User{{Name: "bruceauyeung", RealName: "欧阳钦华"}, {Name: "tanshanshan"}}
+
+	s := newTestServer()
+	handler := s.Handler()
+	if code := get(t, handler, "/api/overall", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("status before metrics are fetched is %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	s.refresh()
+	// this week moves on by refreshes of the server without changing the configured one
+	if week := s.thisWeek(); !week.Equal(getWeekFirstDay(time.Now())) || !Config.ThisWeekFirstDay.Equal(*fakeTime("2016-12-24T00:00:00Z")) {
+		t.Errorf("this week of the server is %v, and configured as %v", week, Config.ThisWeekFirstDay)
+	}
+
+	cases := []struct {
+		url    string
+		merged map[string]int
+		total  int
+	}{
+		{"/api/overall", map[string]int{"bruceauyeung": 5, "tanshanshan": 1}, 6},
+		{"/api/week", map[string]int{"bruceauyeung": 1, "tanshanshan": 0}, 1},
+		{"/api/overall?repos=kubernetes/website", map[string]int{"bruceauyeung": 2}, 2},
+		{"/api/overall?repos=kubernetes/kube*&users=tanshanshan,nobody", map[string]int{"tanshanshan": 1}, 1},
+	}
+	for _, c := range cases {
+		var resp windowResponse
+		if code := get(t, handler, c.url, &resp); code != http.StatusOK {
+			t.Errorf("status of %s is %d", c.url, code)
+			continue
+		}
+		if len(resp.Metrics) != len(c.merged) || resp.Total.Merged != c.total {
+			t.Errorf("%s responds %d users and %d merged PRs in total, want %d and %d",
+				c.url, len(resp.Metrics), resp.Total.Merged, len(c.merged), c.total)
+		}
+		for _, m := range resp.Metrics {
+			if merged, found := c.merged[m.User]; !found || m.Merged != merged {
+				t.Errorf("%s responds %d merged PRs of %s, want %d", c.url, m.Merged, m.User, merged)
+			}
+		}
+	}
+
+	var user userResponse
+	if code := get(t, handler, "/api/users/bruceauyeung?window=overall", &user); code != http.StatusOK {
+		t.Fatalf("status of user is %d", code)
+	}
+	if user.RealName != "欧阳钦华" || user.Windows[WindowOverall].Merged != 5 || len(user.Repos[WindowOverall]) != 2 ||
+		user.Windows[WindowWeek] != nil || len(user.Windows[WindowOverall].Details.Merged) != 1 {
+		t.Errorf("user responds %+v", user)
+	}
+	// details are drilled down by user unless asked for
+	for url, want := range map[string]int{"/api/overall": 0, "/api/overall?details=true": 1} {
+		var resp windowResponse
+		if code := get(t, handler, url, &resp); code != http.StatusOK || len(resp.Metrics[0].Details.Merged) != want ||
+			len(resp.Total.Details.Merged) != want {
+			t.Errorf("%s responds %d details of merged PRs, want %d", url, len(resp.Metrics[0].Details.Merged), want)
+		}
+	}
+	for url, want := range map[string]int{
+		"/api/users/nobody":           http.StatusNotFound,
+		"/api/overall?window=quarter": http.StatusBadRequest,
+		"/api/overall?repos=invalid":  http.StatusBadRequest,
+		"/":                           http.StatusOK,
+		"/favicon.ico":                http.StatusNotFound,
+	} {
+		if code := get(t, handler, url, nil); code != want {
+			t.Errorf("status of %s is %d, want %d", url, code, want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/?users=bruceauyeung", nil))
+	if body := w.Body.String(); !strings.Contains(body, "bruceauyeung(欧阳钦华)") || strings.Contains(body, "tanshanshan") {
+		t.Errorf("dashboard filtered by user is %s", body)
+	}
+}
+
+func Test_serveMetrics(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "", "2016-12-24T00:00:00Z")()
+	s := newTestServer()
+	s.rateLimits = func(repos []*RepoParameters) map[string]*github.RateLimits {
+		return map[string]*github.RateLimits{"": {Core: &github.Rate{Limit: 5000, Remaining: 4000}, Search: &github.Rate{Limit: 30, Remaining: 30}}}
+	}
+	s.refresh()
+
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE github_contrib_merged_prs gauge\n",
+		`github_contrib_merged_prs{user="bruceauyeung",repo="kubernetes/kubernetes",window="overall"} 3` + "\n",
+		`github_contrib_merged_commits{user="bruceauyeung",repo="kubernetes/website",window="week"} 0` + "\n",
+		`github_contrib_open_prs{user="tanshanshan",repo="kubernetes/kubernetes",window="overall",lgtm="false"} 0` + "\n",
+		`github_contrib_api_quota_remaining{host="github.com",resource="core"} 4000` + "\n",
+		`github_contrib_api_quota_limit{host="github.com",resource="search"} 30` + "\n",
+		"github_contrib_fetch_duration_seconds ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, body)
+		}
+	}
+
+	// week statistics is disabled by stat end time
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	w = httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(w.Body.String(), `window="week"`) {
+		t.Errorf("metrics of week should not be exported if stat end time is specified")
+	}
+}
@@ -0,0 +1,303 @@
+package githubstat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// DefaultStorePath is the file of the local store if it is not configured.
+const DefaultStorePath = "githubstat.json"
+
+// StoreCheckpointInterval is the least interval between two saves of the store while it is being changed.
+var StoreCheckpointInterval = 5 * time.Minute
+
+// Store is a local snapshot of synced repos, which is saved as a single JSON file.
+// the whole store is loaded into memory and rewritten by every save, so it is saved once after syncing, and at most
+// once every StoreCheckpointInterval while repos are synced or webhook events are received.
+// PRs are never removed from the store, so history is kept even after PRs are deleted upstream.
+type Store struct {
+	path  string
+	Repos map[string]*StoredRepo // by repo.String()
+
+	savedAt time.Time // when the store was loaded or saved
+	dirty   bool      // whether the store was changed since it was saved
+}
+
+type StoredRepo struct {
+	Owner        string
+	Name         string
+	PullRequests map[int]*StoredPullRequest
+	// merged commits by login of author
+	Commits  map[string][]*PullRequestCommit
+	SyncedAt time.Time
+
+	// high-water marks of incremental sync
+	SyncedSince    time.Time         // stat begin time of the last full sync
+	LastUpdatedAt  time.Time         // the latest update time of synced PRs
+	LastCommitSHAs map[string]string // SHA of the latest synced commit by login of author
+}
+
+type StoredPullRequest struct {
+	PullRequest *github.PullRequest
+	Labels      []string
+	LGTMEvent   *github.IssueEvent   // event that a LGTM label was added, nil if the PR is not LGTM'ed
+	Files       []*github.CommitFile // only synced when path breakdown is enabled
+	ClosedBy    string               // only synced when closed unmerged PRs are listed
+}
+
+// loadStore loads the store from path, an empty store is returned if the file does not exist.
+func loadStore(path string) (*Store, error) {
+	store := &Store{path: path, Repos: make(map[string]*StoredRepo), savedAt: time.Now()}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("invalid store %s : %v", path, err)
+	}
+	return store, nil
+}
+
+// save writes the store to a temporary file first, so that the store is not corrupted if saving is interrupted.
+func (s *Store) save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	s.savedAt = time.Now()
+	s.dirty = false
+	return nil
+}
+
+// checkpoint marks the store changed, and saves it if it was not saved within StoreCheckpointInterval,
+// so that changes are kept if the process is interrupted.
+func (s *Store) checkpoint() error {
+	s.dirty = true
+	if time.Since(s.savedAt) < StoreCheckpointInterval {
+		return nil
+	}
+	return s.save()
+}
+
+// flush saves the store if it was changed since it was saved.
+func (s *Store) flush() error {
+	if !s.dirty {
+		return nil
+	}
+	return s.save()
+}
+
+// repo returns the stored repo, which is created if it was never synced.
+func (s *Store) repo(repo *RepoParameters) *StoredRepo {
+	key := repo.String()
+	r, found := s.Repos[key]
+	if !found {
+		r = &StoredRepo{
+			Owner:        *repo.OwnerName,
+			Name:         *repo.RepoName,
+			PullRequests: make(map[int]*StoredPullRequest),
+			Commits:      make(map[string][]*PullRequestCommit),
+		}
+		s.Repos[key] = r
+	}
+	if r.LastCommitSHAs == nil {
+		r.LastCommitSHAs = make(map[string]string)
+	}
+	return r
+}
+
+// addCommits adds merged commits of author, commits already stored are replaced.
+func (r *StoredRepo) addCommits(author string, commits []*PullRequestCommit) {
+	bySHA := make(map[string]int)
+	for i, c := range r.Commits[author] {
+		bySHA[*c.RepositoryCommit.SHA] = i
+	}
+	for _, c := range commits {
+		if i, found := bySHA[*c.RepositoryCommit.SHA]; found {
+			r.Commits[author][i] = c
+		} else {
+			bySHA[*c.RepositoryCommit.SHA] = len(r.Commits[author])
+			r.Commits[author] = append(r.Commits[author], c)
+		}
+	}
+}
+
+// storeProvider queries PRs and commits of a host from the local store without any API call,
+// so that metrics of any period after the stat begin time of syncing are computed instantly.
+type storeProvider struct {
+	store  *Store
+	host   string
+	period statPeriod // period which PRs and commits are queried in, the configured one if it is zero
+}
+
+func newStoreProvider(host string, period statPeriod) *storeProvider {
+	store, err := loadStore(Config.StorePath)
+	if err != nil {
+		panic(err)
+	}
+	return &storeProvider{store: store, host: host, period: period}
+}
+
+func (p *storeProvider) repo(owner string, repo string) (*StoredRepo, error) {
+	r := &RepoParameters{OwnerName: &owner, RepoName: &repo, Host: p.host}
+	stored, found := p.store.Repos[r.String()]
+	if !found {
+		return nil, fmt.Errorf("%s is not synced into store %s", r, p.store.path)
+	}
+	return stored, nil
+}
+
+func (p *storeProvider) pullRequest(owner string, repo string, number int) (*StoredPullRequest, error) {
+	stored, err := p.repo(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	pr, found := stored.PullRequests[number]
+	if !found {
+		return nil, fmt.Errorf("pull request %s is not synced into store %s", pullRequestKey(owner, repo, number), p.store.path)
+	}
+	return pr, nil
+}
+
+// listPullRequests lists stored PRs in state, which are sorted by less and filtered the same way as listing from API.
+func (p *storeProvider) listPullRequests(owner string, repo string, state string,
+	less func(a, b *github.PullRequest) bool, filter func(*github.PullRequest) (bool, bool)) ([]*github.PullRequest, error) {
+	stored, err := p.repo(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var allPRs []*github.PullRequest
+	for _, pr := range stored.PullRequests {
+		if *pr.PullRequest.State != state {
+			continue
+		}
+		if keep, _ := filter(pr.PullRequest); keep {
+			allPRs = append(allPRs, pr.PullRequest)
+		}
+	}
+	sort.Slice(allPRs, func(i, j int) bool { return less(allPRs[i], allPRs[j]) })
+	return allPRs, nil
+}
+
+func (p *storeProvider) ListRepositories(owner string) ([]*github.Repository, error) {
+	var allRepos []*github.Repository
+	for key, r := range p.store.Repos {
+		repo := &RepoParameters{OwnerName: &r.Owner, RepoName: &r.Name, Host: p.host}
+		if r.Owner != owner || repo.String() != key {
+			continue
+		}
+		login := r.Owner
+		name := r.Name
+		allRepos = append(allRepos, &github.Repository{Owner: &github.User{Login: &login}, Name: &name})
+	}
+	sort.Slice(allRepos, func(i, j int) bool { return *allRepos[i].Name < *allRepos[j].Name })
+	return allRepos, nil
+}
+
+func (p *storeProvider) ListOpenPullRequests(owner string, repo string) ([]*github.PullRequest, error) {
+	return p.listPullRequests(owner, repo, "open",
+		func(a, b *github.PullRequest) bool { return a.CreatedAt.After(*b.CreatedAt) }, p.period.orConfigured().filterOpen)
+}
+
+func (p *storeProvider) ListClosedPullRequests(owner string, repo string) ([]*github.PullRequest, error) {
+	return p.listPullRequests(owner, repo, "closed",
+		func(a, b *github.PullRequest) bool { return a.UpdatedAt.After(*b.UpdatedAt) }, p.period.orConfigured().filterClosed)
+}
+
+func (p *storeProvider) ListUpdatedPullRequests(owner string, repo string, since time.Time) ([]*github.PullRequest, error) {
+	stored, err := p.repo(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var allPRs []*github.PullRequest
+	for _, pr := range stored.PullRequests {
+		if !pr.PullRequest.UpdatedAt.Before(since) {
+			allPRs = append(allPRs, pr.PullRequest)
+		}
+	}
+	sort.Slice(allPRs, func(i, j int) bool { return allPRs[i].UpdatedAt.After(*allPRs[j].UpdatedAt) })
+	return allPRs, nil
+}
+
+func (p *storeProvider) GetPullRequest(owner string, repo string, number int) (*github.PullRequest, error) {
+	pr, err := p.pullRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	return pr.PullRequest, nil
+}
+
+func (p *storeProvider) GetLabelNames(owner string, repo string, number int) ([]string, error) {
+	pr, err := p.pullRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	return pr.Labels, nil
+}
+
+func (p *storeProvider) GetLatestLGTMEvent(owner string, repo string, number int) (*github.IssueEvent, error) {
+	pr, err := p.pullRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	if pr.LGTMEvent == nil {
+		return nil, fmt.Errorf("no LGTM event found")
+	}
+	return pr.LGTMEvent, nil
+}
+
+func (p *storeProvider) ListFiles(owner string, repo string, number int) ([]*github.CommitFile, error) {
+	pr, err := p.pullRequest(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	return pr.Files, nil
+}
+
+func (p *storeProvider) GetClosedBy(owner string, repo string, number int) (string, error) {
+	pr, err := p.pullRequest(owner, repo, number)
+	if err != nil {
+		return "", err
+	}
+	return pr.ClosedBy, nil
+}
+
+// ListMergedCommits lists stored commits of author merged in the period.
+func (p *storeProvider) ListMergedCommits(owner string, repo string, author string) ([]*PullRequestCommit, error) {
+	stored, err := p.repo(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var prCommits []*PullRequestCommit
+	for _, c := range stored.Commits[author] {
+		if c.MergedAt != nil && p.period.orConfigured().contains(c.MergedAt) {
+			prCommits = append(prCommits, c)
+		}
+	}
+	return prCommits, nil
+}
@@ -0,0 +1,141 @@
+package githubstat
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Sync(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	users, storePath := Config.Users, Config.StorePath
+	defer func() { Config.Users, Config.StorePath = users, storePath }()
+	Config.Users = []User{{Name: "bruceauyeung"}, {Name: "tanshanshan"}}
+	dir, err := ioutil.TempDir("", "githubstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	Config.StorePath = filepath.Join(dir, "githubstat.json")
+	f := newPullRequestFakeGitHub(t)
+
+	repo, err := ParseRepo("kubernetes/kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := loadStore(Config.StorePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// everything since stat begin time is synced regardless of stat end time
+	period := statPeriod{begin: Config.StatBeginTime}
+	syncRepo(&restProvider{client: f.client(), period: period}, store.repo(repo), repo, false, period)
+	if last := store.repo(repo).LastUpdatedAt; !last.Equal(*fakeTime("2016-12-31T00:00:00Z")) {
+		t.Errorf("PRs are synced until %v, want 2016-12-31 after stat end time", last)
+	}
+	if err := store.save(); err != nil {
+		t.Fatal(err)
+	}
+	// metrics are computed from the store without the server
+	f.Close()
+
+	fetch := func() *PullRequestMetrics {
+		m := &PullRequestMetricsRequest{source: SourceStore}
+		m.SetParameters(&MetricsParameters{Repos: []*RepoParameters{repo}})
+		return merge(m.FetchMetrics().(*AllPullRequestMetrics).Overall)[0]
+	}
+	if got := fetch(); got.Merged != 2 || got.MergedCommits != 2 || got.LGTMed != 1 || got.NonLGTMed != 1 || got.Created != 3 {
+		t.Errorf("overall metrics of bruceauyeung from store is %+v", *got)
+	}
+	// re-slice by another period
+	Config.StatEndTime = *fakeTime("2016-12-01T00:00:00Z")
+	if got := fetch(); got.Merged != 1 || got.MergedCommits != 1 || got.LGTMed != 0 || got.NonLGTMed != 1 || got.Created != 1 {
+		t.Errorf("overall metrics of bruceauyeung before 2016-12-01 from store is %+v", *got)
+	}
+}
+
+func Test_syncRepoIncrementally(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "", "2016-12-24T00:00:00Z")()
+	users := Config.Users
+	defer func() { Config.Users = users }()
+	Config.Users = []User{{Name: "bruceauyeung"}}
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+
+	repo, err := ParseRepo("kubernetes/kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &Store{Repos: make(map[string]*StoredRepo)}
+	stored := store.repo(repo)
+	period := statPeriod{begin: Config.StatBeginTime}
+	provider := &restProvider{client: f.client(), period: period}
+	syncRepo(provider, stored, repo, false, period)
+	if !stored.LastUpdatedAt.Equal(*fakeTime("2016-12-31T00:00:00Z")) || stored.LastCommitSHAs["bruceauyeung"] != "aaa" {
+		t.Errorf("high-water marks are %v and %q, want 2016-12-31 and aaa", stored.LastUpdatedAt, stored.LastCommitSHAs["bruceauyeung"])
+	}
+
+	// a PR merged and a commit pushed after the first sync
+	f.addPullRequest(11, "bruceauyeung", "closed", "2017-01-02T00:00:00Z", "2017-01-03T00:00:00Z", "2017-01-03T00:00:00Z")
+	f.addCommit("eee", "bruceauyeung", "fix doc", 11)
+	f.commits = append(f.commits[len(f.commits)-1:], f.commits[:len(f.commits)-1]...)
+	f.requests = nil
+	syncRepo(provider, stored, repo, false, period)
+
+	if _, found := stored.PullRequests[11]; !found || len(stored.PullRequests) != 8 {
+		t.Errorf("PR #11 should be synced besides 7 PRs synced before, got %d PRs", len(stored.PullRequests))
+	}
+	if len(stored.Commits["bruceauyeung"]) != 3 || stored.LastCommitSHAs["bruceauyeung"] != "eee" {
+		t.Errorf("commit eee should be synced besides 2 commits synced before, got %d commits", len(stored.Commits["bruceauyeung"]))
+	}
+	for _, path := range f.requests {
+		if path == "/repos/kubernetes/kubernetes/issues/4" || path == "/repos/kubernetes/kubernetes/pulls/4" {
+			t.Errorf("PR #4 was not updated but requested again: %s", path)
+		}
+	}
+}
+
+func Test_storeCheckpoint(t *testing.T) {
+	interval := StoreCheckpointInterval
+	defer func() { StoreCheckpointInterval = interval }()
+	dir, err := ioutil.TempDir("", "githubstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "githubstat.json")
+	store, err := loadStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := ParseRepo("kubernetes/kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := func() int {
+		saved, err := loadStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(saved.Repos)
+	}
+
+	// the store is not saved again within the interval
+	store.repo(repo)
+	if err := store.checkpoint(); err != nil || saved() != 0 {
+		t.Errorf("store is saved by a checkpoint right after it was loaded, %v", err)
+	}
+	if err := store.flush(); err != nil || saved() != 1 {
+		t.Errorf("changed store is not saved by flush, %v", err)
+	}
+	if store.dirty {
+		t.Errorf("store is still changed after flush")
+	}
+	StoreCheckpointInterval = 0
+	repo.RepoName = stringOf("website")
+	store.repo(repo)
+	if err := store.checkpoint(); err != nil || saved() != 2 || store.dirty {
+		t.Errorf("store is not saved by a checkpoint after the interval, %v", err)
+	}
+}
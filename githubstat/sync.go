@@ -0,0 +1,138 @@
+package githubstat
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// mergedCommitsSinceLister is implemented by providers which can list merged commits newer than the latest
+// synced one, merged commits are listed all over again by other providers.
+type mergedCommitsSinceLister interface {
+	ListMergedCommitsSince(owner string, repo string, author string, lastSHA string) ([]*PullRequestCommit, error)
+}
+
+// Sync fetches PRs, commits, labels and LGTM events of repos from API, and saves them into the store.
+// everything since stat begin time is synced regardless of stat end time, so that metrics of any period after
+// stat begin time can be queried from the store by source "store".
+// repos synced before are synced incrementally, i.e. only PRs updated and commits pushed since the last sync
+// are fetched, unless full is true or stat begin time is earlier than that of the last full sync.
+func Sync(repos []*RepoParameters, full bool) {
+	store, err := loadStore(Config.StorePath)
+	if err != nil {
+		panic(err)
+	}
+	// the period has no end, so that everything since stat begin time is synced
+	period := statPeriod{begin: Config.StatBeginTime}
+	m := &PullRequestMetricsRequest{param: &MetricsParameters{Repos: repos}, source: SourceAPI, period: period}
+	m.expandRepos()
+	for _, repo := range m.param.Repos {
+		syncRepo(m.providerOf(repo.Host), store.repo(repo), repo, full, period)
+		// checkpoint after every repo, so that most synced repos are kept if syncing is interrupted
+		if err := store.checkpoint(); err != nil {
+			panic(err)
+		}
+	}
+	if err := store.flush(); err != nil {
+		panic(err)
+	}
+}
+
+// syncRepo syncs PRs and commits of a repo in the period, providers list them in the same period.
+func syncRepo(provider Provider, stored *StoredRepo, repo *RepoParameters, full bool, period statPeriod) {
+	ownerName := *repo.OwnerName
+	repoName := *repo.RepoName
+	full = full || stored.LastUpdatedAt.IsZero() || period.begin.Before(stored.SyncedSince)
+
+	var prs []*github.PullRequest
+	if full {
+		fmt.Printf("%s : syncing open pull requests\n", repo)
+		openPRs, err := provider.ListOpenPullRequests(ownerName, repoName)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s : syncing closed pull requests\n", repo)
+		closedPRs, err := provider.ListClosedPullRequests(ownerName, repoName)
+		if err != nil {
+			panic(err)
+		}
+		prs = append(openPRs, closedPRs...)
+		stored.SyncedSince = period.begin
+	} else {
+		fmt.Printf("%s : syncing pull requests updated since %v\n", repo, stored.LastUpdatedAt)
+		updatedPRs, err := provider.ListUpdatedPullRequests(ownerName, repoName, stored.LastUpdatedAt)
+		if err != nil {
+			panic(err)
+		}
+		// PRs are kept the same way as a full sync does
+		for _, pr := range updatedPRs {
+			filter := period.filterClosed
+			if *pr.State == "open" {
+				filter = period.filterOpen
+			}
+			if keep, _ := filter(pr); keep {
+				prs = append(prs, pr)
+			}
+		}
+	}
+	for _, pr := range prs {
+		stored.PullRequests[*pr.Number] = syncPullRequest(provider, ownerName, repoName, pr)
+		if pr.UpdatedAt.After(stored.LastUpdatedAt) {
+			stored.LastUpdatedAt = *pr.UpdatedAt
+		}
+	}
+
+	fmt.Printf("%s : syncing merged commits\n", repo)
+	for _, user := range Config.Users {
+		login := user.login(repo.Host)
+		var commits []*PullRequestCommit
+		var err error
+		if lister, ok := provider.(mergedCommitsSinceLister); ok && !full {
+			commits, err = lister.ListMergedCommitsSince(ownerName, repoName, login, stored.LastCommitSHAs[login])
+		} else {
+			commits, err = provider.ListMergedCommits(ownerName, repoName, login)
+		}
+		if err != nil {
+			panic(err)
+		}
+		// commits are listed from the latest one
+		if len(commits) != 0 {
+			stored.LastCommitSHAs[login] = *commits[0].RepositoryCommit.SHA
+		}
+		stored.addCommits(login, commits)
+	}
+	stored.SyncedAt = time.Now()
+}
+
+// syncPullRequest fetches everything of a PR that metrics need.
+func syncPullRequest(provider Provider, owner string, repo string, pr *github.PullRequest) *StoredPullRequest {
+	var err error
+	number := *pr.Number
+	if pr.MergedAt != nil {
+		// fill in all other blank fields, such as Commits, Additions and Deletions
+		if pr, err = provider.GetPullRequest(owner, repo, number); err != nil {
+			panic(err)
+		}
+	}
+	stored := &StoredPullRequest{PullRequest: pr}
+	if stored.Labels, err = provider.GetLabelNames(owner, repo, number); err != nil {
+		panic(err)
+	}
+	if hasLGTMLabel(stored.Labels) {
+		if stored.LGTMEvent, err = provider.GetLatestLGTMEvent(owner, repo, number); err != nil {
+			panic(err)
+		}
+	}
+	if Config.PathBreakdown && pr.MergedAt != nil {
+		if stored.Files, err = provider.ListFiles(owner, repo, number); err != nil {
+			panic(err)
+		}
+	}
+	if Config.ListClosedUnmerged && pr.MergedAt == nil && pr.ClosedAt != nil {
+		if stored.ClosedBy, err = provider.GetClosedBy(owner, repo, number); err != nil {
+			panic(err)
+		}
+	}
+	return stored
+}
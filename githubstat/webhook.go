@@ -0,0 +1,273 @@
+package githubstat
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// webhookPayload is the part of payloads of webhook events that metrics need.
+type webhookPayload struct {
+	Action      string              `json:"action"`
+	PullRequest *github.PullRequest `json:"pull_request"`
+	Issue       *struct {
+		Number      int       `json:"number"`
+		PullRequest *struct{} `json:"pull_request"` // not nil if the issue is a PR
+	} `json:"issue"`
+	Repository *struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+			Name  string `json:"name"` // owner of repository of push events may have name only
+		} `json:"owner"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+	Ref     string `json:"ref"`
+	Commits []struct {
+		Author struct {
+			Username string `json:"username"`
+		} `json:"author"`
+	} `json:"commits"`
+}
+
+// verifyWebhookSignature verifies HMAC of body signed by the secret, signatures of SHA-256 are preferred to SHA-1.
+func verifyWebhookSignature(r *http.Request, body []byte, secret string) bool {
+	signature := r.Header.Get("X-Hub-Signature-256")
+	prefix := "sha256="
+	newHash := sha256.New
+	if signature == "" {
+		signature = r.Header.Get("X-Hub-Signature")
+		prefix = "sha1="
+		newHash = func() hash.Hash { return sha1.New() }
+	}
+	if !strings.HasPrefix(signature, prefix) {
+		return false
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// serveWebhook receives pull_request, pull_request_review, issues and push events, updates PRs and commits
+// they refer to in the store, and recomputes metrics of users they affect in the repo from the store.
+func (s *Server) serveWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		http.Error(w, "webhook is disabled because webhookSecret is not configured", http.StatusNotFound)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "webhook events must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !verifyWebhookSignature(r, body, Config.Server.WebhookSecret) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, fmt.Sprintf("invalid payload : %v", err), http.StatusBadRequest)
+		return
+	}
+	// GitHub Enterprise Server tells its host name
+	host := r.Header.Get("X-GitHub-Enterprise-Host")
+	if host == DefaultHost {
+		host = ""
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	logins, err := s.handleEvent(event, host, &payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(logins) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "%s event is ignored\n", event)
+		return
+	}
+	fmt.Fprintf(w, "metrics of %s are updated\n", strings.Join(logins, ", "))
+}
+
+// handleEvent updates the store by the event, and returns logins of users whose metrics are recomputed.
+// events of repos which are not synced into the store are ignored.
+func (s *Server) handleEvent(event string, host string, payload *webhookPayload) (logins []string, err error) {
+	if payload.Repository == nil {
+		return nil, nil
+	}
+	owner := payload.Repository.Owner.Login
+	if owner == "" {
+		owner = payload.Repository.Owner.Name
+	}
+	name := payload.Repository.Name
+	repo := &RepoParameters{OwnerName: &owner, RepoName: &name, Host: host}
+
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	stored, found := s.store.Repos[repo.String()]
+	if !found {
+		return nil, nil
+	}
+	// providers panic on errors, like fetching metrics does
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to handle %s event of %s : %v", event, repo, r)
+		}
+	}()
+	provider := s.eventProvider(host)
+
+	var pr *github.PullRequest
+	switch event {
+	case "pull_request", "pull_request_review":
+		pr = payload.PullRequest
+	case "issues":
+		// labels of PRs are changed by issues events
+		if payload.Issue == nil || payload.Issue.PullRequest == nil {
+			return nil, nil
+		}
+		if pr, err = provider.GetPullRequest(owner, name, payload.Issue.Number); err != nil {
+			return nil, err
+		}
+	case "push":
+		if payload.Ref != "refs/heads/"+payload.Repository.DefaultBranch {
+			return nil, nil
+		}
+		logins = s.syncPushedCommits(provider, stored, repo, payload)
+	default:
+		return nil, nil
+	}
+	if pr != nil {
+		// high-water marks of incremental sync are left untouched, so PRs missed by webhook are synced later
+		stored.PullRequests[*pr.Number] = syncPullRequest(provider, owner, name, pr)
+		if pr.User != nil && pr.User.Login != nil {
+			logins = []string{*pr.User.Login}
+		}
+	}
+
+	users := usersOfLogins(host, logins)
+	if len(users) == 0 {
+		return nil, nil
+	}
+	stored.SyncedAt = time.Now()
+	// the store is flushed by the next refresh if the checkpoint is skipped
+	if err := s.store.checkpoint(); err != nil {
+		return nil, err
+	}
+	s.recompute(repo, users)
+	logins = nil
+	for _, user := range users {
+		logins = append(logins, user.Name)
+	}
+	return logins, nil
+}
+
+// syncPushedCommits syncs commits of users who pushed to the default branch, and returns their logins.
+func (s *Server) syncPushedCommits(provider Provider, stored *StoredRepo, repo *RepoParameters, payload *webhookPayload) []string {
+	var authors []string
+	for _, c := range payload.Commits {
+		authors = append(authors, c.Author.Username)
+	}
+	var logins []string
+	for _, user := range usersOfLogins(repo.Host, authors) {
+		login := user.login(repo.Host)
+		var commits []*PullRequestCommit
+		var err error
+		if lister, ok := provider.(mergedCommitsSinceLister); ok {
+			commits, err = lister.ListMergedCommitsSince(*repo.OwnerName, *repo.RepoName, login, stored.LastCommitSHAs[login])
+		} else {
+			commits, err = provider.ListMergedCommits(*repo.OwnerName, *repo.RepoName, login)
+		}
+		if err != nil {
+			panic(err)
+		}
+		if len(commits) != 0 {
+			stored.LastCommitSHAs[login] = *commits[0].RepositoryCommit.SHA
+		}
+		stored.addCommits(login, commits)
+		logins = append(logins, login)
+	}
+	return logins
+}
+
+// usersOfLogins returns configured users with any of the logins on the host.
+func usersOfLogins(host string, logins []string) []User {
+	var users []User
+	for _, user := range Config.Users {
+		for _, login := range logins {
+			if user.login(host) == login {
+				users = append(users, user)
+				break
+			}
+		}
+	}
+	return users
+}
+
+// recompute computes metrics of users in the repo from the store, and replaces their latest metrics.
+func (s *Server) recompute(repo *RepoParameters, users []User) {
+	m := &PullRequestMetricsRequest{
+		param:     &MetricsParameters{Repos: []*RepoParameters{repo}},
+		source:    SourceStore,
+		providers: map[string]Provider{repo.Host: &storeProvider{store: s.store, host: repo.Host}},
+		users:     users,
+		// this week is the same as that of the latest metrics
+		weekFirstDay: s.thisWeek(),
+		// the previous window is kept as that of the latest metrics
+		noCompare: true,
+	}
+	recomputed := m.FetchMetrics().(*AllPullRequestMetrics)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.metrics == nil {
+		return
+	}
+	replace := func(latest []*PullRequestMetrics, recomputed []*PullRequestMetrics) []*PullRequestMetrics {
+		var replaced []*PullRequestMetrics
+		for _, metrics := range latest {
+			if metrics.Repo == repo.String() && containsUser(users, metrics.User) {
+				continue
+			}
+			replaced = append(replaced, metrics)
+		}
+		return append(replaced, recomputed...)
+	}
+	// the latest metrics are replaced instead of modified, handlers may still be serving those got by latest()
+	overall := s.metrics.OverallPullRequestMetrics
+	s.metrics = &AllPullRequestMetrics{
+		OverallPullRequestMetrics: &OverallPullRequestMetrics{
+			Overall:           replace(overall.Overall, recomputed.Overall),
+			Previous:          overall.Previous,
+			PreviousBeginTime: overall.PreviousBeginTime,
+			PreviousEndTime:   overall.PreviousEndTime,
+		},
+		WeekPullRequestMetrics: &WeekPullRequestMetrics{Week: replace(s.metrics.Week, recomputed.Week)},
+	}
+}
+
+func containsUser(users []User, name string) bool {
+	for _, user := range users {
+		if user.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package githubstat
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func postWebhook(t *testing.T, handler http.Handler, event string, payload interface{}, secret string) int {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	r := httptest.NewRequest("POST", "/webhook", bytes.NewReader(body))
+	r.Header.Set("X-GitHub-Event", event)
+	r.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+func Test_serveWebhook(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "", "2016-12-24T00:00:00Z")()
+	users, server := Config.Users, Config.Server
+	defer func() { Config.Users, Config.Server = users, server }()
+	Config.Users = []User{{Name: "bruceauyeung"}, {Name: "tanshanshan"}}
+	Config.Server.WebhookSecret = "secret"
+	dir, err := ioutil.TempDir("", "githubstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+
+	s := newTestServer()
+	s.store = &Store{path: filepath.Join(dir, "store.json"), Repos: make(map[string]*StoredRepo)}
+	repo, _ := ParseRepo("kubernetes/kubernetes")
+	s.store.repo(repo)
+	var providers int
+	s.eventProvider = func(host string) Provider {
+		providers++
+		return &restProvider{client: f.client()}
+	}
+	s.refresh()
+	handler := s.Handler()
+
+	repository := map[string]interface{}{"name": "kubernetes", "owner": map[string]string{"login": "kubernetes"},
+		"default_branch": "master"}
+	merged := map[string]interface{}{"action": "closed", "pull_request": f.findPullRequest(5), "repository": repository}
+	if code := postWebhook(t, handler, "pull_request", merged, "wrong secret"); code != http.StatusUnauthorized {
+		t.Errorf("status of invalid signature is %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := postWebhook(t, handler, "pull_request", merged, "secret"); code != http.StatusOK {
+		t.Fatalf("status of pull_request event is %d", code)
+	}
+	push := map[string]interface{}{"ref": "refs/heads/master", "repository": repository,
+		"commits": []interface{}{map[string]interface{}{"author": map[string]string{"username": "bruceauyeung"}}}}
+	if code := postWebhook(t, handler, "push", push, "secret"); code != http.StatusOK {
+		t.Fatalf("status of push event is %d", code)
+	}
+	other := map[string]interface{}{"ref": "refs/heads/master", "repository": map[string]interface{}{"name": "website",
+		"owner": map[string]string{"login": "kubernetes"}}}
+	if code := postWebhook(t, handler, "push", other, "secret"); code != http.StatusAccepted {
+		t.Errorf("status of event of repo not in store is %d, want %d", code, http.StatusAccepted)
+	}
+
+	// events of synced repos have their own providers
+	if providers != 2 {
+		t.Errorf("%d providers are created for 2 events, want 2", providers)
+	}
+	if pr := s.store.Repos["kubernetes/kubernetes"].PullRequests[5]; pr == nil || pr.PullRequest.MergedAt == nil {
+		t.Errorf("merged PR #5 is not stored")
+	}
+	// metrics of bruceauyeung in kubernetes/kubernetes are recomputed from the store, others are left untouched
+	var resp windowResponse
+	get(t, handler, "/api/overall?repos=kubernetes/kubernetes", &resp)
+	for _, m := range resp.Metrics {
+		want := PullRequestMetrics{User: "bruceauyeung", Merged: 1, MergedCommits: 2}
+		if m.User == "tanshanshan" {
+			want = PullRequestMetrics{User: "tanshanshan", Merged: 1, MergedCommits: 1}
+		}
+		if m.Merged != want.Merged || m.MergedCommits != want.MergedCommits {
+			t.Errorf("metrics of %s are %d merged PRs and %d merged commits, want %d and %d",
+				m.User, m.Merged, m.MergedCommits, want.Merged, want.MergedCommits)
+		}
+	}
+	get(t, handler, "/api/overall?repos=kubernetes/website&users=bruceauyeung", &resp)
+	if resp.Total.Merged != 2 {
+		t.Errorf("metrics of other repos should be left untouched, got %d merged PRs", resp.Total.Merged)
+	}
+}
+
+func Test_serveWebhookWithCompare(t *testing.T) {
+	defer setStatPeriod("2016-10-01T00:00:00Z", "2016-12-30T00:00:00Z", "2016-12-24T00:00:00Z")()
+	users, server, compare := Config.Users, Config.Server, Config.Compare
+	defer func() { Config.Users, Config.Server, Config.Compare = users, server, compare }()
+	Config.Users = []User{{Name: "bruceauyeung"}, {Name: "tanshanshan"}}
+	Config.Server.WebhookSecret = "secret"
+	Config.Compare = CompareQuarter
+	dir, err := ioutil.TempDir("", "githubstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f := newPullRequestFakeGitHub(t)
+	defer f.Close()
+
+	s := newTestServer()
+	s.store = &Store{path: filepath.Join(dir, "store.json"), Repos: make(map[string]*StoredRepo)}
+	repo, _ := ParseRepo("kubernetes/kubernetes")
+	s.store.repo(repo)
+	s.eventProvider = func(host string) Provider { return &restProvider{client: f.client()} }
+	s.refresh()
+	begin, end := *fakeTime("2016-07-02T00:00:00Z"), *fakeTime("2016-10-01T00:00:00Z")
+	s.metrics.Previous = []*PullRequestMetrics{
+		{User: "bruceauyeung", Repo: "kubernetes/kubernetes", Merged: 7},
+		{User: "bruceauyeung", Repo: "kubernetes/website", Merged: 9},
+	}
+	s.metrics.PreviousBeginTime, s.metrics.PreviousEndTime = begin, end
+
+	repository := map[string]interface{}{"name": "kubernetes", "owner": map[string]string{"login": "kubernetes"},
+		"default_branch": "master"}
+	push := map[string]interface{}{"ref": "refs/heads/master", "repository": repository,
+		"commits": []interface{}{map[string]interface{}{"author": map[string]string{"username": "bruceauyeung"}}}}
+	if code := postWebhook(t, s.Handler(), "push", push, "secret"); code != http.StatusOK {
+		t.Fatalf("status of push event is %d", code)
+	}
+
+	// the previous window of the latest metrics is kept
+	latest, _ := s.latest()
+	if !latest.PreviousBeginTime.Equal(begin) || !latest.PreviousEndTime.Equal(end) || len(latest.Previous) != 2 {
+		t.Fatalf("previous window is %v ~ %v with %d metrics", latest.PreviousBeginTime, latest.PreviousEndTime, len(latest.Previous))
+	}
+	for _, m := range latest.Previous {
+		if want := map[string]int{"kubernetes/kubernetes": 7, "kubernetes/website": 9}[m.Repo]; m.Merged != want {
+			t.Errorf("previous metrics of %s in %s are %d merged PRs, want %d", m.User, m.Repo, m.Merged, want)
+		}
+	}
+}
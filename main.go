@@ -3,7 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
-	"strings"
+	"os"
 
 	"time"
 
@@ -14,8 +14,54 @@ func main() {
 	start := time.Now()
 	flagMetrics := flag.String("metrics", "", "available metrics: (pr)")
 	dimension := flag.String("dimension", "", "available dimension: (overall)")
+	closedUnmerged := flag.Bool("closed-unmerged", false, "list PRs closed without being merged and who closed them")
+	compare := flag.String("compare", "", "compare metrics with the previous window of the stat period: (week, month, quarter)")
+	goalGate := flag.String("goal-gate", "", "exit with code 1 if any goal is not met by values: (achieved, projected)")
+	details := flag.Bool("details", false, "list PRs and commits behind every number of metrics")
+	api := flag.String("api", "", "api used to fetch pull requests: (rest, graphql)")
+	compareAPI := flag.Bool("compare-api", false, "fetch pr metrics by both rest and graphql api and show them side by side")
+	source := flag.String("source", "", "source of pull requests: (api, git, store)")
+	fullSync := flag.Bool("full-sync", false, "sync all PRs and commits again instead of those updated since the last sync")
+	listRepos := flag.Bool("list-repos", false, "list repos resolved from patterns and exclusions without fetching metrics")
+	plan := flag.Bool("plan", false, "estimate API calls, quotas and duration of fetching metrics without fetching them")
+	dryRun := flag.Bool("dry-run", false, "print the report at once instead of sending it on schedule")
+	format := flag.String("format", githubstat.OutputFormatText, "output format of pr metrics: (text, html)")
+	output := flag.String("o", "", "file to write the html report to, e.g. report.html, or directory to write badges to")
+	fixtureMode := flag.String("fixture-mode", "", "save API interactions to fixture files, or replay them without network: (record, replay)")
 	flag.Parse()
 
+	if *closedUnmerged {
+		githubstat.Config.ListClosedUnmerged = true
+	}
+	if *details {
+		githubstat.Config.Details = true
+	}
+	if *compare != "" {
+		githubstat.Config.Compare = *compare
+	}
+	if *goalGate != "" && *goalGate != githubstat.GoalGateAchieved && *goalGate != githubstat.GoalGateProjected {
+		fmt.Printf("unknown goal gate : %s, must be %q or %q\n", *goalGate, githubstat.GoalGateAchieved, githubstat.GoalGateProjected)
+		os.Exit(1)
+	}
+	if *goalGate == githubstat.GoalGateProjected && githubstat.Config.StatEndTime.IsZero() {
+		fmt.Println("goal gate projected requires statEndTime in config.toml to project values to")
+		os.Exit(1)
+	}
+	// progress of fetching is printed to stdout, so the html report is only written to a file
+	if *format == githubstat.OutputFormatHTML && *output == "" {
+		fmt.Println("html format requires a file to write the report to by -o")
+		os.Exit(1)
+	}
+	if *api != "" {
+		githubstat.Config.API = *api
+	}
+	if *source != "" {
+		githubstat.Config.Source = *source
+	}
+	if *fixtureMode != "" {
+		githubstat.Config.FixtureMode = *fixtureMode
+	}
+
 	if flagMetrics == nil || *flagMetrics == "" {
 		flagMetrics = &githubstat.Config.Metrics
 		if flagMetrics == nil || *flagMetrics == "" {
@@ -35,23 +81,110 @@ func main() {
 	}
 
 	parameters := flag.Args()
+	// "sync" command saves PRs of repos into the local store instead of showing metrics,
+	// "serve" command fetches metrics on schedule and serves them over HTTP,
+	// "report" command sends metrics to chat on schedule,
+	// "badge" command writes SVG badges and contribution cards of users.
+	var command string
+	if len(parameters) != 0 && (parameters[0] == "sync" || parameters[0] == "serve" || parameters[0] == "report" ||
+		parameters[0] == "badge") {
+		command = parameters[0]
+		parameters = parameters[1:]
+	}
 
 	if len(parameters) == 0 {
 		parameters = githubstat.Config.Repos
 
 	}
 	for _, repoStr := range parameters {
-		repo := strings.Split(repoStr, "/")
-		if len(repo) != 2 {
-			fmt.Printf("invalid repository name : %s, must be of format 'ownername/reponame'\n", repoStr)
+		repo, err := githubstat.ParseRepo(repoStr)
+		if err != nil {
+			fmt.Println(err)
+			continue
 		}
-		metricsParameters.Repos = append(metricsParameters.Repos,
-			&githubstat.RepoParameters{&repo[0], &repo[1]})
+		metricsParameters.Repos = append(metricsParameters.Repos, repo)
+	}
+
+	if *listRepos {
+		repos := githubstat.ResolveRepos(metricsParameters.Repos)
+		for _, repo := range repos {
+			fmt.Println(repo)
+		}
+		fmt.Printf("%d repos\n", len(repos))
+		return
+	}
+	if *plan {
+		githubstat.EstimatePlan(metricsParameters.Repos).Show()
+		return
+	}
+	if *compareAPI {
+		githubstat.CompareAPIs(metricsParameters.Repos)
+		return
+	}
+	switch command {
+	case "sync":
+		githubstat.Sync(metricsParameters.Repos, *fullSync)
+		fmt.Printf("sync finished and spent %v minutes", time.Since(start).Minutes())
+		return
+	case "serve":
+		if err := githubstat.NewServer(metricsParameters.Repos).Serve(); err != nil {
+			panic(err)
+		}
+		return
+	case "report":
+		githubstat.Report(metricsParameters.Repos, *dryRun)
+		return
+	case "badge":
+		githubstat.WriteBadges(metricsParameters.Repos, *output)
+		return
 	}
 
 	metricsRequest.SetParameters(&metricsParameters)
 	metrics := metricsRequest.FetchMetrics()
-	metrics.Show()
+	switch *format {
+	case githubstat.OutputFormatText:
+		metrics.Show()
+	case githubstat.OutputFormatHTML:
+		all, ok := metrics.(*githubstat.AllPullRequestMetrics)
+		if !ok {
+			fmt.Println("html format is only supported by pr metrics")
+			return
+		}
+		f, err := os.Create(*output)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		if err := all.WriteHTML(f); err != nil {
+			panic(err)
+		}
+		fmt.Printf("html report is written to %s\n", *output)
+	default:
+		fmt.Printf("unknown format : %s\n", *format)
+		return
+	}
 	elapsed := time.Since(start)
 	fmt.Printf("stats finished and spent %v minutes", elapsed.Minutes())
+
+	if *goalGate != "" {
+		all, ok := metrics.(*githubstat.AllPullRequestMetrics)
+		if !ok {
+			fmt.Println("\ngoals are only supported by pr metrics")
+			os.Exit(1)
+		}
+		unmet, err := all.UnmetGoals(*goalGate)
+		if err != nil {
+			panic(err)
+		}
+		for _, p := range unmet {
+			fmt.Printf("\ngoal of %s is not met : %s %d / %d", p.User, p.Metric, p.Achieved, p.Target)
+			if p.Projected >= 0 {
+				fmt.Printf(", projected %d", p.Projected)
+			}
+		}
+		if len(unmet) != 0 {
+			fmt.Println()
+			os.Exit(1)
+		}
+	}
 }